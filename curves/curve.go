@@ -0,0 +1,252 @@
+// Package curves implements the elliptic curves used by JOSE that are not
+// provided by crypto/elliptic, so that they can be used with crypto/ecdsa.
+//
+// The arithmetic is implemented using math/big for any short Weierstrass
+// curve y² = x³ + ax + b. It is not constant time, and is meant for the
+// interoperability with tokens using these curves rather than for high
+// volume signing.
+package curves
+
+import (
+	"crypto/elliptic"
+	"math/big"
+)
+
+// curve implements elliptic.Curve for a short Weierstrass curve with an
+// arbitrary `a` coefficient, whereas elliptic.CurveParams assumes a = -3
+type curve struct {
+	params *elliptic.CurveParams
+	a      *big.Int
+}
+
+// newCurve creates a curve from the hexadecimal representation of its
+// parameters
+func newCurve(name string, bitSize int, p, a, b, n, gx, gy string) *curve {
+	return &curve{
+		params: &elliptic.CurveParams{
+			Name:    name,
+			BitSize: bitSize,
+			P:       fromHex(p),
+			N:       fromHex(n),
+			B:       fromHex(b),
+			Gx:      fromHex(gx),
+			Gy:      fromHex(gy),
+		},
+		a: fromHex(a),
+	}
+}
+
+func fromHex(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("curves: invalid hexadecimal parameter " + s)
+	}
+	return v
+}
+
+// Params returns the parameters of the curve. The `a` coefficient is not
+// part of them.
+func (c *curve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+// IsOnCurve reports whether the point (x, y) is on the curve
+func (c *curve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+
+	// y² = x³ + ax + b
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Add(rhs, c.a)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, c.params.B)
+	rhs.Mod(rhs, p)
+
+	return y2.Cmp(rhs) == 0
+}
+
+// jacobianPoint is a point (X/Z², Y/Z³) in Jacobian coordinates. The
+// point at infinity has Z = 0.
+type jacobianPoint struct {
+	x, y, z *big.Int
+}
+
+func (c *curve) toJacobian(x, y *big.Int) *jacobianPoint {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return &jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	return &jacobianPoint{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+// toAffine converts `pt` back to affine coordinates, using (0, 0) for the
+// point at infinity as crypto/elliptic does
+func (c *curve) toAffine(pt *jacobianPoint) (*big.Int, *big.Int) {
+	if pt.z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	p := c.params.P
+	zInv := new(big.Int).ModInverse(pt.z, p)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+
+	x := new(big.Int).Mul(pt.x, zInv2)
+	x.Mod(x, p)
+	y := new(big.Int).Mul(pt.y, zInv2)
+	y.Mul(y, zInv)
+	y.Mod(y, p)
+	return x, y
+}
+
+// double computes 2·pt, see
+// https://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian.html#doubling-dbl-2007-bl
+func (c *curve) double(pt *jacobianPoint) *jacobianPoint {
+	p := c.params.P
+	if pt.z.Sign() == 0 || pt.y.Sign() == 0 {
+		return &jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+
+	xx := new(big.Int).Mul(pt.x, pt.x)
+	xx.Mod(xx, p)
+	yy := new(big.Int).Mul(pt.y, pt.y)
+	yy.Mod(yy, p)
+	yyyy := new(big.Int).Mul(yy, yy)
+	yyyy.Mod(yyyy, p)
+	zz := new(big.Int).Mul(pt.z, pt.z)
+	zz.Mod(zz, p)
+
+	// S = 4·X·YY
+	s := new(big.Int).Mul(pt.x, yy)
+	s.Lsh(s, 2)
+	s.Mod(s, p)
+
+	// M = 3·XX + a·ZZ²
+	m := new(big.Int).Lsh(xx, 1)
+	m.Add(m, xx)
+	if c.a.Sign() != 0 {
+		azz := new(big.Int).Mul(zz, zz)
+		azz.Mul(azz, c.a)
+		m.Add(m, azz)
+	}
+	m.Mod(m, p)
+
+	// X3 = M² - 2·S
+	x3 := new(big.Int).Mul(m, m)
+	x3.Sub(x3, new(big.Int).Lsh(s, 1))
+	x3.Mod(x3, p)
+
+	// Y3 = M·(S - X3) - 8·YYYY
+	y3 := new(big.Int).Sub(s, x3)
+	y3.Mul(y3, m)
+	y3.Sub(y3, new(big.Int).Lsh(yyyy, 3))
+	y3.Mod(y3, p)
+
+	// Z3 = 2·Y·Z
+	z3 := new(big.Int).Mul(pt.y, pt.z)
+	z3.Lsh(z3, 1)
+	z3.Mod(z3, p)
+
+	return &jacobianPoint{x3, y3, z3}
+}
+
+// add computes pt1 + pt2, see
+// https://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian.html#addition-add-2007-bl
+func (c *curve) add(pt1, pt2 *jacobianPoint) *jacobianPoint {
+	p := c.params.P
+	if pt1.z.Sign() == 0 {
+		return pt2
+	}
+	if pt2.z.Sign() == 0 {
+		return pt1
+	}
+
+	z1z1 := new(big.Int).Mul(pt1.z, pt1.z)
+	z1z1.Mod(z1z1, p)
+	z2z2 := new(big.Int).Mul(pt2.z, pt2.z)
+	z2z2.Mod(z2z2, p)
+
+	u1 := new(big.Int).Mul(pt1.x, z2z2)
+	u1.Mod(u1, p)
+	u2 := new(big.Int).Mul(pt2.x, z1z1)
+	u2.Mod(u2, p)
+
+	s1 := new(big.Int).Mul(pt1.y, pt2.z)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, p)
+	s2 := new(big.Int).Mul(pt2.y, pt1.z)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, p)
+
+	if u1.Cmp(u2) == 0 {
+		if s1.Cmp(s2) == 0 {
+			return c.double(pt1)
+		}
+		return &jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+
+	// H = U2 - U1, R = S2 - S1
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, p)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, p)
+
+	hh := new(big.Int).Mul(h, h)
+	hh.Mod(hh, p)
+	hhh := new(big.Int).Mul(hh, h)
+	hhh.Mod(hhh, p)
+	v := new(big.Int).Mul(u1, hh)
+	v.Mod(v, p)
+
+	// X3 = R² - H³ - 2·U1·H²
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, hhh)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	x3.Mod(x3, p)
+
+	// Y3 = R·(U1·H² - X3) - S1·H³
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	y3.Sub(y3, new(big.Int).Mul(s1, hhh))
+	y3.Mod(y3, p)
+
+	// Z3 = H·Z1·Z2
+	z3 := new(big.Int).Mul(pt1.z, pt2.z)
+	z3.Mul(z3, h)
+	z3.Mod(z3, p)
+
+	return &jacobianPoint{x3, y3, z3}
+}
+
+// Add returns the sum of (x1, y1) and (x2, y2)
+func (c *curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return c.toAffine(c.add(c.toJacobian(x1, y1), c.toJacobian(x2, y2)))
+}
+
+// Double returns 2·(x, y)
+func (c *curve) Double(x, y *big.Int) (*big.Int, *big.Int) {
+	return c.toAffine(c.double(c.toJacobian(x, y)))
+}
+
+// ScalarMult returns k·(x, y), where k is a number in big-endian form
+func (c *curve) ScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	base := c.toJacobian(x, y)
+	result := &jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			result = c.double(result)
+			if (b>>uint(bit))&1 == 1 {
+				result = c.add(result, base)
+			}
+		}
+	}
+	return c.toAffine(result)
+}
+
+// ScalarBaseMult returns k·G, where G is the base point of the curve and
+// k is a number in big-endian form
+func (c *curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
@@ -0,0 +1,126 @@
+package curves_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/repenno/jwx-opa/curves"
+)
+
+func fromHex(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("Invalid hexadecimal value %s", s)
+	}
+	return v
+}
+
+func TestCurves(t *testing.T) {
+	tests := []struct {
+		curve  elliptic.Curve
+		x2, y2 string // Coordinates of 2·G
+		d      string // Private key of the known public key (x, y)
+		x, y   string
+	}{
+		{
+			curve: curves.Secp256k1(),
+			x2:    "C6047F9441ED7D6D3045406E95C07CD85C778E4B8CEF3CA7ABAC09B95C709EE5",
+			y2:    "1AE168FEA63DC339A3C58419466CEAEEF7F632653266D0E1236431A950CFE52A",
+		},
+		// https://tools.ietf.org/html/rfc7027#appendix-A.1
+		{
+			curve: curves.BrainpoolP256r1(),
+			d:     "81DB1EE100150FF2EA338D708271BE38300CB54241D79950F77B063039804F1D",
+			x:     "44106E913F92BC02A1705D9953A8414DB95E1AAA49E81D9E85F929A8E3100BE5",
+			y:     "8AB4846F11CACCB73CE49CBDD120F5A900A69FD32C272223F789EF10EB089BDC",
+		},
+		// https://tools.ietf.org/html/rfc7027#appendix-A.2
+		{
+			curve: curves.BrainpoolP384r1(),
+			d:     "1E20F5E048A5886F1F157C74E91BDE2B98C8B52D58E5003D57053FC4B0BD65D6F15EB5D1EE1610DF870795143627D042",
+			x:     "68B665DD91C195800650CDD363C625F4E742E8134667B767B1B476793588F885AB698C852D4A6E77A252D6380FCAF068",
+			y:     "55BC91A39C9EC01DEE36017B7D673A931236D2F1F5C83942D049E3FA20607493E0D038FF2FD30C2AB67D15C85F7FAA59",
+		},
+		// https://tools.ietf.org/html/rfc7027#appendix-A.3
+		{
+			curve: curves.BrainpoolP512r1(),
+			d:     "16302FF0DBBB5A8D733DAB7141C1B45ACBC8715939677F6A56850A38BD87BD59B09E80279609FF333EB9D4C061231FB26F92EEB04982A5F1D1764CAD57665422",
+			x:     "0A420517E406AAC0ACDCE90FCD71487718D3B953EFD7FBEC5F7F27E28C6149999397E91E029E06457DB2D3E640668B392C2A7E737A7F0BF04436D11640FD09FD",
+			y:     "72E6882E8DB28AAD36237CD25D580DB23783961C8DC52DFA2EC138AD472A0FCEF3887CF62B623B2A87DE5C588301EA3E5FC269B373B60724F5E82A6AD147FDE7",
+		},
+	}
+
+	for _, test := range tests {
+		params := test.curve.Params()
+		t.Run(params.Name, func(t *testing.T) {
+			if !test.curve.IsOnCurve(params.Gx, params.Gy) {
+				t.Fatal("Base point should be on the curve")
+			}
+			if test.curve.IsOnCurve(params.Gx, new(big.Int).Add(params.Gy, big.NewInt(1))) {
+				t.Fatal("Point should not be on the curve")
+			}
+
+			// Without a known value, 2·G computed in different ways must agree
+			x2, y2 := test.curve.Double(params.Gx, params.Gy)
+			if test.x2 != "" {
+				x2, y2 = fromHex(t, test.x2), fromHex(t, test.y2)
+			}
+			if !test.curve.IsOnCurve(x2, y2) {
+				t.Fatal("2·G should be on the curve")
+			}
+			for name, compute := range map[string]func() (*big.Int, *big.Int){
+				"ScalarBaseMult": func() (*big.Int, *big.Int) { return test.curve.ScalarBaseMult([]byte{2}) },
+				"Double":         func() (*big.Int, *big.Int) { return test.curve.Double(params.Gx, params.Gy) },
+				"Add":            func() (*big.Int, *big.Int) { return test.curve.Add(params.Gx, params.Gy, params.Gx, params.Gy) },
+			} {
+				x, y := compute()
+				if x.Cmp(x2) != 0 || y.Cmp(y2) != 0 {
+					t.Fatalf("%s: unexpected 2·G (%x, %x)", name, x, y)
+				}
+			}
+
+			if test.d != "" {
+				x, y := test.curve.ScalarBaseMult(fromHex(t, test.d).Bytes())
+				if x.Cmp(fromHex(t, test.x)) != 0 || y.Cmp(fromHex(t, test.y)) != 0 {
+					t.Fatalf("Unexpected public key (%x, %x)", x, y)
+				}
+			}
+
+			// The order of the base point must be N
+			x, y := test.curve.ScalarBaseMult(params.N.Bytes())
+			if x.Sign() != 0 || y.Sign() != 0 {
+				t.Fatal("N·G should be the point at infinity")
+			}
+			x, y = test.curve.ScalarBaseMult(new(big.Int).Sub(params.N, big.NewInt(1)).Bytes())
+			if x.Cmp(params.Gx) != 0 || new(big.Int).Add(y, params.Gy).Cmp(params.P) != 0 {
+				t.Fatal("(N-1)·G should be -G")
+			}
+		})
+		t.Run(params.Name+" ECDSA", func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(test.curve, rand.Reader)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %s", err.Error())
+			}
+			if !test.curve.IsOnCurve(key.X, key.Y) {
+				t.Fatal("Public key should be on the curve")
+			}
+			digest := sha256.Sum256([]byte("Lorem ipsum"))
+			r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+			if err != nil {
+				t.Fatalf("Failed to sign: %s", err.Error())
+			}
+			if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+				t.Fatal("Failed to verify signature")
+			}
+			digest[0] ^= 1
+			if ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+				t.Fatal("Verification of a modified digest should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package curves
+
+import (
+	"crypto/elliptic"
+	"sync"
+)
+
+var (
+	secp256k1     *curve
+	secp256k1Once sync.Once
+)
+
+// Secp256k1 returns the secp256k1 curve, as described in section 2.4.1 of
+// https://www.secg.org/sec2-v2.pdf and used by the ES256K algorithm of
+// https://tools.ietf.org/html/rfc8812#section-3.2
+func Secp256k1() elliptic.Curve {
+	secp256k1Once.Do(func() {
+		secp256k1 = newCurve("secp256k1", 256,
+			"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F",
+			"0",
+			"7",
+			"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141",
+			"79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798",
+			"483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8",
+		)
+	})
+	return secp256k1
+}
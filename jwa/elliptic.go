@@ -5,7 +5,12 @@ type EllipticCurveAlgorithm string
 
 // Supported values for EllipticCurveAlgorithm
 const (
-	P256 EllipticCurveAlgorithm = "P-256"
-	P384 EllipticCurveAlgorithm = "P-384"
-	P521 EllipticCurveAlgorithm = "P-521"
+	BP256     EllipticCurveAlgorithm = "BP-256"
+	BP384     EllipticCurveAlgorithm = "BP-384"
+	BP512     EllipticCurveAlgorithm = "BP-512"
+	Ed25519   EllipticCurveAlgorithm = "Ed25519"
+	P256      EllipticCurveAlgorithm = "P-256"
+	P384      EllipticCurveAlgorithm = "P-384"
+	P521      EllipticCurveAlgorithm = "P-521"
+	Secp256k1 EllipticCurveAlgorithm = "secp256k1"
 )
@@ -8,13 +8,15 @@ import (
 // KeyType represents the key type ("kty") that are supported
 type KeyType string
 
-var keyTypeAlg = map[string]struct{}{"EC": {}, "oct": {}, "RSA": {}}
+var keyTypeAlg = map[string]struct{}{"AKP": {}, "EC": {}, "oct": {}, "OKP": {}, "RSA": {}}
 
 // Supported values for KeyType
 const (
+	AKP            KeyType = "AKP" // Algorithm key pair (used to represent ML-DSA keys)
 	EC             KeyType = "EC"  // Elliptic Curve
 	InvalidKeyType KeyType = ""    // Invalid KeyType
 	OctetSeq       KeyType = "oct" // Octet sequence (used to represent symmetric keys)
+	OKP            KeyType = "OKP" // Octet key pair (used to represent Edwards curve keys)
 	RSA            KeyType = "RSA" // RSA
 )
 
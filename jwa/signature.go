@@ -3,21 +3,49 @@ package jwa
 import (
 	"github.com/pkg/errors"
 	"strconv"
+	"sync"
 )
 
 // SignatureAlgorithm represents the various signature algorithms as described in https://tools.ietf.org/html/rfc7518#section-3.1
 type SignatureAlgorithm string
 
-var signatureAlg = map[string]struct{}{"ES256": {}, "ES384": {}, "ES512": {}, "HS256": {}, "HS384": {}, "HS512": {}, "PS256": {}, "PS384": {}, "PS512": {}, "RS256": {}, "RS384": {}, "RS512": {}, "none": {}}
+var signatureAlg = map[string]struct{}{"EdDSA": {}, "ES256": {}, "ES256K": {}, "ES384": {}, "ES512": {}, "ESB256": {}, "ESB384": {}, "ESB512": {}, "HS256": {}, "HS384": {}, "HS512": {}, "ML-DSA-44": {}, "ML-DSA-65": {}, "ML-DSA-87": {}, "PS256": {}, "PS384": {}, "PS512": {}, "RS256": {}, "RS384": {}, "RS512": {}, "none": {}}
+
+// signatureAlgMutex guards signatureAlg, signatureKeyType and
+// registeredSignatureAlg, which are extended by RegisterSignatureAlgorithm
+var signatureAlgMutex sync.RWMutex
+
+// registeredSignatureAlg lists the algorithms added with
+// RegisterSignatureAlgorithm, which are the only ones that may be removed
+var registeredSignatureAlg = map[SignatureAlgorithm]struct{}{}
+
+// signatureKeyType maps each signature algorithm to the key type it operates on
+var signatureKeyType = map[SignatureAlgorithm]KeyType{
+	EdDSA: OKP,
+	ES256: EC, ES256K: EC, ES384: EC, ES512: EC,
+	ESB256: EC, ESB384: EC, ESB512: EC,
+	HS256: OctetSeq, HS384: OctetSeq, HS512: OctetSeq,
+	MLDSA44: AKP, MLDSA65: AKP, MLDSA87: AKP,
+	PS256: RSA, PS384: RSA, PS512: RSA,
+	RS256: RSA, RS384: RSA, RS512: RSA,
+}
 
 // Supported values for SignatureAlgorithm
 const (
-	ES256       SignatureAlgorithm = "ES256" // ECDSA using P-256 and SHA-256
-	ES384       SignatureAlgorithm = "ES384" // ECDSA using P-384 and SHA-384
-	ES512       SignatureAlgorithm = "ES512" // ECDSA using P-521 and SHA-512
-	HS256       SignatureAlgorithm = "HS256" // HMAC using SHA-256
-	HS384       SignatureAlgorithm = "HS384" // HMAC using SHA-384
-	HS512       SignatureAlgorithm = "HS512" // HMAC using SHA-512
+	EdDSA       SignatureAlgorithm = "EdDSA"     // EdDSA signature algorithms
+	ES256       SignatureAlgorithm = "ES256"     // ECDSA using P-256 and SHA-256
+	ES256K      SignatureAlgorithm = "ES256K"    // ECDSA using secp256k1 and SHA-256
+	ES384       SignatureAlgorithm = "ES384"     // ECDSA using P-384 and SHA-384
+	ES512       SignatureAlgorithm = "ES512"     // ECDSA using P-521 and SHA-512
+	ESB256      SignatureAlgorithm = "ESB256"    // ECDSA using brainpoolP256r1 and SHA-256
+	ESB384      SignatureAlgorithm = "ESB384"    // ECDSA using brainpoolP384r1 and SHA-384
+	ESB512      SignatureAlgorithm = "ESB512"    // ECDSA using brainpoolP512r1 and SHA-512
+	HS256       SignatureAlgorithm = "HS256"     // HMAC using SHA-256
+	HS384       SignatureAlgorithm = "HS384"     // HMAC using SHA-384
+	HS512       SignatureAlgorithm = "HS512"     // HMAC using SHA-512
+	MLDSA44     SignatureAlgorithm = "ML-DSA-44" // ML-DSA-44 post-quantum signatures
+	MLDSA65     SignatureAlgorithm = "ML-DSA-65" // ML-DSA-65 post-quantum signatures
+	MLDSA87     SignatureAlgorithm = "ML-DSA-87" // ML-DSA-87 post-quantum signatures
 	NoSignature SignatureAlgorithm = "none"
 	PS256       SignatureAlgorithm = "PS256" // RSASSA-PSS using SHA256 and MGF1-SHA256
 	PS384       SignatureAlgorithm = "PS384" // RSASSA-PSS using SHA384 and MGF1-SHA384
@@ -40,19 +68,72 @@ func (signature *SignatureAlgorithm) Accept(value interface{}) error {
 	default:
 		return errors.Errorf(`invalid type for jwa.SignatureAlgorithm: %T`, value)
 	}
-	_, ok := signatureAlg[tmp.String()]
-	if !ok {
+	if !isSignatureAlgorithm(tmp.String()) {
 		return errors.Errorf("Unknown signature algorithm")
 	}
 	*signature = tmp
 	return nil
 }
 
+// RegisterSignatureAlgorithm adds `alg` to the signature algorithms that
+// are accepted, so that values such as the "alg" header parameter may use
+// it. `keyType` is the type of key the algorithm operates on, and may be
+// InvalidKeyType for keys that have no JWK representation. The signer and
+// verifier of the algorithm are registered separately, using the Register
+// functions of the jws/sign and jws/verify packages. The algorithm is
+// removed with UnregisterSignatureAlgorithm.
+func RegisterSignatureAlgorithm(alg SignatureAlgorithm, keyType KeyType) error {
+	if alg == NoValue {
+		return errors.New(`missing signature algorithm name`)
+	}
+
+	signatureAlgMutex.Lock()
+	defer signatureAlgMutex.Unlock()
+	if _, ok := signatureAlg[alg.String()]; ok {
+		return errors.Errorf(`signature algorithm %s is already registered`, alg)
+	}
+	signatureAlg[alg.String()] = struct{}{}
+	signatureKeyType[alg] = keyType
+	registeredSignatureAlg[alg] = struct{}{}
+	return nil
+}
+
+// UnregisterSignatureAlgorithm removes `alg`, which must have been added
+// with RegisterSignatureAlgorithm, from the signature algorithms that are
+// accepted
+func UnregisterSignatureAlgorithm(alg SignatureAlgorithm) error {
+	signatureAlgMutex.Lock()
+	defer signatureAlgMutex.Unlock()
+	if _, ok := registeredSignatureAlg[alg]; !ok {
+		return errors.Errorf(`signature algorithm %s is not registered`, alg)
+	}
+	delete(signatureAlg, alg.String())
+	delete(signatureKeyType, alg)
+	delete(registeredSignatureAlg, alg)
+	return nil
+}
+
+// isSignatureAlgorithm reports whether `name` is a known signature algorithm
+func isSignatureAlgorithm(name string) bool {
+	signatureAlgMutex.RLock()
+	defer signatureAlgMutex.RUnlock()
+	_, ok := signatureAlg[name]
+	return ok
+}
+
 // String returns the string representation of a SignatureAlgorithm
 func (signature SignatureAlgorithm) String() string {
 	return string(signature)
 }
 
+// KeyType returns the type of key used by the signature algorithm,
+// or InvalidKeyType if the algorithm does not use a key
+func (signature SignatureAlgorithm) KeyType() KeyType {
+	signatureAlgMutex.RLock()
+	defer signatureAlgMutex.RUnlock()
+	return signatureKeyType[signature]
+}
+
 // UnmarshalJSON unmarshals and checks data as Signature Algorithm
 func (signature *SignatureAlgorithm) UnmarshalJSON(data []byte) error {
 	var quote byte = '"'
@@ -66,8 +147,7 @@ func (signature *SignatureAlgorithm) UnmarshalJSON(data []byte) error {
 	} else {
 		quoted = string(data)
 	}
-	_, ok := signatureAlg[quoted]
-	if !ok {
+	if !isSignatureAlgorithm(quoted) {
 		return errors.Errorf("Unknown signature algorithm")
 	}
 	*signature = SignatureAlgorithm(quoted)
@@ -1,14 +1,39 @@
 package jwk
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"encoding/json"
+	"fmt"
 	"math/big"
 
 	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/curves"
 	"github.com/repenno/jwx-opa/jwa"
 )
 
+// ellipticCurves maps the "crv" parameter of EC keys to their curve
+var ellipticCurves = map[jwa.EllipticCurveAlgorithm]func() elliptic.Curve{
+	jwa.BP256:     curves.BrainpoolP256r1,
+	jwa.BP384:     curves.BrainpoolP384r1,
+	jwa.BP512:     curves.BrainpoolP512r1,
+	jwa.P256:      elliptic.P256,
+	jwa.P384:      elliptic.P384,
+	jwa.P521:      elliptic.P521,
+	jwa.Secp256k1: curves.Secp256k1,
+}
+
+// curveName returns the "crv" parameter of EC keys on `curve`
+func curveName(curve elliptic.Curve) (jwa.EllipticCurveAlgorithm, error) {
+	for name, f := range ellipticCurves {
+		if f() == curve {
+			return name, nil
+		}
+	}
+	return "", errors.Errorf(`unsupported curve %s`, curve.Params().Name)
+}
+
 func newECDSAPublicKey(key *ecdsa.PublicKey) (*ECDSAPublicKey, error) {
 
 	var hdr StandardHeaders
@@ -59,17 +84,14 @@ func (k *ECDSAPublicKey) GenerateKey(keyJSON *RawKeyJSON) error {
 	x.SetBytes(keyJSON.X.Bytes())
 	y.SetBytes(keyJSON.Y.Bytes())
 
-	var curve elliptic.Curve
-	switch keyJSON.Crv {
-	case jwa.P256:
-		curve = elliptic.P256()
-	case jwa.P384:
-		curve = elliptic.P384()
-	case jwa.P521:
-		curve = elliptic.P521()
-	default:
+	newCurve, ok := ellipticCurves[keyJSON.Crv]
+	if !ok {
 		return errors.Errorf(`invalid curve name %s`, keyJSON.Crv)
 	}
+	curve := newCurve()
+	if !curve.IsOnCurve(&x, &y) {
+		return errors.Errorf(`point is not on curve %s`, keyJSON.Crv)
+	}
 
 	*k = ECDSAPublicKey{
 		StandardHeaders: &keyJSON.StandardHeaders,
@@ -117,3 +139,68 @@ func (k *ECDSAPrivateKey) GenerateKey(keyJSON *RawKeyJSON) error {
 
 	return nil
 }
+
+// MarshalJSON serializes the EC-DSA public key in JWK format
+func (k *ECDSAPublicKey) MarshalJSON() ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no ecdsa.PublicKey associated with it`)
+	}
+	raw := newRawKeyJSON(k.StandardHeaders, jwa.EC)
+	if err := setECDSAPublicParams(raw, k.key); err != nil {
+		return nil, err
+	}
+	return json.Marshal(raw)
+}
+
+// MarshalJSON serializes the EC-DSA private key in JWK format
+func (k *ECDSAPrivateKey) MarshalJSON() ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no ecdsa.PrivateKey associated with it`)
+	}
+	raw := newRawKeyJSON(k.StandardHeaders, jwa.EC)
+	if err := setECDSAPublicParams(raw, &k.key.PublicKey); err != nil {
+		return nil, err
+	}
+	// See GenerateKey for the length of the private key
+	n := k.key.Params().N
+	raw.D = padBytes(k.key.D.Bytes(), (new(big.Int).Sub(n, big.NewInt(1)).BitLen()+7)>>3)
+	return json.Marshal(raw)
+}
+
+func setECDSAPublicParams(raw *RawKeyJSON, key *ecdsa.PublicKey) error {
+	crv, err := curveName(key.Curve)
+	if err != nil {
+		return err
+	}
+	size := (key.Params().BitSize + 7) / 8
+	raw.Crv = crv
+	raw.X = padBytes(key.X.Bytes(), size)
+	raw.Y = padBytes(key.Y.Bytes(), size)
+	return nil
+}
+
+// Thumbprint returns the JWK Thumbprint of the EC-DSA public key
+func (k *ECDSAPublicKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no ecdsa.PublicKey associated with it`)
+	}
+	return ecdsaThumbprint(hash, k.key)
+}
+
+// Thumbprint returns the JWK Thumbprint of the public part of the EC-DSA private key
+func (k *ECDSAPrivateKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no ecdsa.PrivateKey associated with it`)
+	}
+	return ecdsaThumbprint(hash, &k.key.PublicKey)
+}
+
+func ecdsaThumbprint(hash crypto.Hash, key *ecdsa.PublicKey) ([]byte, error) {
+	crv, err := curveName(key.Curve)
+	if err != nil {
+		return nil, err
+	}
+	size := (key.Params().BitSize + 7) / 8
+	return thumbprint(hash, fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`,
+		crv, encodeInt(key.X, size), encodeInt(key.Y, size)))
+}
@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/repenno/jwx-opa/curves"
 	"github.com/repenno/jwx-opa/jwa"
 	"github.com/repenno/jwx-opa/jwk"
 )
@@ -245,4 +246,58 @@ func TestECDSA(t *testing.T) {
 			t.Fatalf("Key Generation should fail")
 		}
 	})
+	t.Run("Curves", func(t *testing.T) {
+		tests := []struct {
+			crv   string
+			curve elliptic.Curve
+		}{
+			{crv: "secp256k1", curve: curves.Secp256k1()},
+			{crv: "BP-256", curve: curves.BrainpoolP256r1()},
+			{crv: "BP-384", curve: curves.BrainpoolP384r1()},
+			{crv: "BP-512", curve: curves.BrainpoolP512r1()},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.crv, func(t *testing.T) {
+				privateKey, err := ecdsa.GenerateKey(test.curve, rand.Reader)
+				if err != nil {
+					t.Fatalf("Failed to generate key: %s", err.Error())
+				}
+				for _, rawKey := range []interface{}{privateKey, &privateKey.PublicKey} {
+					jwkKey, err := jwk.New(rawKey)
+					if err != nil {
+						t.Fatalf("Failed to create JWK: %s", err.Error())
+					}
+					buf, err := json.Marshal(jwkKey)
+					if err != nil {
+						t.Fatalf("Failed to marshal JWK: %s", err.Error())
+					}
+					var raw map[string]interface{}
+					if err := json.Unmarshal(buf, &raw); err != nil {
+						t.Fatalf("Failed to unmarshal JWK: %s", err.Error())
+					}
+					if raw["crv"] != test.crv {
+						t.Fatalf("Expected crv %s, got %v", test.crv, raw["crv"])
+					}
+					set, err := jwk.ParseBytes(buf)
+					if err != nil {
+						t.Fatalf("Failed to parse JWK: %s", err.Error())
+					}
+					parsed, err := set.Keys[0].Materialize()
+					if err != nil {
+						t.Fatalf("Failed to materialize key: %s", err.Error())
+					}
+					if !reflect.DeepEqual(parsed, rawKey) {
+						t.Fatal("Mismatched key after round trip")
+					}
+				}
+			})
+		}
+	})
+	t.Run("Point not on curve", func(t *testing.T) {
+		const jwkSrc = `{"kty":"EC","crv":"secp256k1","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"}`
+		if _, err := jwk.ParseString(jwkSrc); err == nil {
+			t.Fatal("Parsing a point that is not on the curve should fail")
+		}
+	})
 }
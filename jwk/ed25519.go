@@ -0,0 +1,144 @@
+package jwk
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+)
+
+func newEd25519PublicKey(key ed25519.PublicKey) (*Ed25519PublicKey, error) {
+
+	var hdr StandardHeaders
+	err := hdr.Set(KeyTypeKey, jwa.OKP)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to set Key Type")
+	}
+
+	return &Ed25519PublicKey{
+		StandardHeaders: &hdr,
+		key:             key,
+	}, nil
+}
+
+func newEd25519PrivateKey(key ed25519.PrivateKey) (*Ed25519PrivateKey, error) {
+
+	var hdr StandardHeaders
+	err := hdr.Set(KeyTypeKey, jwa.OKP)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to set Key Type")
+	}
+
+	return &Ed25519PrivateKey{
+		StandardHeaders: &hdr,
+		key:             key,
+	}, nil
+}
+
+// Materialize returns the Ed25519 public key represented by this JWK
+func (k Ed25519PublicKey) Materialize() (interface{}, error) {
+	return k.key, nil
+}
+
+// Materialize returns the Ed25519 private key represented by this JWK
+func (k Ed25519PrivateKey) Materialize() (interface{}, error) {
+	return k.key, nil
+}
+
+// GenerateKey creates a Ed25519PublicKey from JWK format, as described in
+// https://tools.ietf.org/html/rfc8037#section-2
+func (k *Ed25519PublicKey) GenerateKey(keyJSON *RawKeyJSON) error {
+
+	if keyJSON.X == nil || keyJSON.Crv == "" {
+		return errors.Errorf("Missing mandatory key parameters X or Crv")
+	}
+	if keyJSON.Crv != jwa.Ed25519 {
+		return errors.Errorf(`invalid curve name %s`, keyJSON.Crv)
+	}
+	if keyJSON.X.Len() != ed25519.PublicKeySize {
+		return errors.Errorf("Failed to generate public key. Incorrect X value")
+	}
+
+	*k = Ed25519PublicKey{
+		StandardHeaders: &keyJSON.StandardHeaders,
+		key:             ed25519.PublicKey(keyJSON.X.Bytes()),
+	}
+	return nil
+}
+
+// GenerateKey creates a Ed25519PrivateKey from JWK format. The "d"
+// parameter holds the seed of the private key.
+func (k *Ed25519PrivateKey) GenerateKey(keyJSON *RawKeyJSON) error {
+
+	if keyJSON.D == nil {
+		return errors.Errorf("Missing mandatory key parameter D")
+	}
+	ed25519PublicKey := &Ed25519PublicKey{}
+	err := ed25519PublicKey.GenerateKey(keyJSON)
+	if err != nil {
+		return errors.Wrap(err, `failed to generate public key`)
+	}
+	if keyJSON.D.Len() != ed25519.SeedSize {
+		return errors.Errorf("Failed to generate private key. Incorrect D value")
+	}
+	privateKey := ed25519.NewKeyFromSeed(keyJSON.D.Bytes())
+	if !bytes.Equal(privateKey.Public().(ed25519.PublicKey), ed25519PublicKey.key) {
+		return errors.Errorf("Failed to generate private key. X does not match D")
+	}
+
+	k.key = privateKey
+	k.StandardHeaders = &keyJSON.StandardHeaders
+
+	return nil
+}
+
+// MarshalJSON serializes the Ed25519 public key in JWK format
+func (k *Ed25519PublicKey) MarshalJSON() ([]byte, error) {
+	if len(k.key) != ed25519.PublicKeySize {
+		return nil, errors.New(`key has no ed25519.PublicKey associated with it`)
+	}
+	raw := newRawKeyJSON(k.StandardHeaders, jwa.OKP)
+	raw.Crv = jwa.Ed25519
+	raw.X = []byte(k.key)
+	return json.Marshal(raw)
+}
+
+// MarshalJSON serializes the Ed25519 private key in JWK format
+func (k *Ed25519PrivateKey) MarshalJSON() ([]byte, error) {
+	if len(k.key) != ed25519.PrivateKeySize {
+		return nil, errors.New(`key has no ed25519.PrivateKey associated with it`)
+	}
+	raw := newRawKeyJSON(k.StandardHeaders, jwa.OKP)
+	raw.Crv = jwa.Ed25519
+	raw.X = []byte(k.key.Public().(ed25519.PublicKey))
+	raw.D = k.key.Seed()
+	return json.Marshal(raw)
+}
+
+// Thumbprint returns the JWK Thumbprint of the Ed25519 public key
+func (k *Ed25519PublicKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+	if len(k.key) != ed25519.PublicKeySize {
+		return nil, errors.New(`key has no ed25519.PublicKey associated with it`)
+	}
+	return ed25519Thumbprint(hash, k.key)
+}
+
+// Thumbprint returns the JWK Thumbprint of the public part of the Ed25519 private key
+func (k *Ed25519PrivateKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+	if len(k.key) != ed25519.PrivateKeySize {
+		return nil, errors.New(`key has no ed25519.PrivateKey associated with it`)
+	}
+	return ed25519Thumbprint(hash, k.key.Public().(ed25519.PublicKey))
+}
+
+// ed25519Thumbprint uses the members required by
+// https://tools.ietf.org/html/rfc8037#section-2
+func ed25519Thumbprint(hash crypto.Hash, key ed25519.PublicKey) ([]byte, error) {
+	return thumbprint(hash, fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`,
+		jwa.Ed25519, base64.RawURLEncoding.EncodeToString(key)))
+}
@@ -0,0 +1,109 @@
+package jwk_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jwk"
+)
+
+// exampleEd25519Key is taken from https://tools.ietf.org/html/rfc8037#appendix-A.1
+const exampleEd25519Key = `{"kty":"OKP","crv":"Ed25519",
+ "d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+ "x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+
+func TestEd25519(t *testing.T) {
+
+	t.Run("Parse private key", func(t *testing.T) {
+		set, err := jwk.ParseString(exampleEd25519Key)
+		if err != nil {
+			t.Fatalf("Failed to parse JWK: %s", err.Error())
+		}
+		key, ok := set.Keys[0].(*jwk.Ed25519PrivateKey)
+		if !ok {
+			t.Fatalf("Expected *jwk.Ed25519PrivateKey, got %T", set.Keys[0])
+		}
+		if key.GetKeyType() != jwa.OKP {
+			t.Fatalf("Expected key type OKP, got %s", key.GetKeyType())
+		}
+		rawKey, err := key.Materialize()
+		if err != nil {
+			t.Fatalf("Failed to materialize key: %s", err.Error())
+		}
+		privateKey, ok := rawKey.(ed25519.PrivateKey)
+		if !ok {
+			t.Fatalf("Expected ed25519.PrivateKey, got %T", rawKey)
+		}
+		publicKey, err := jwk.GetPublicKey(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to get public key: %s", err.Error())
+		}
+		expected, _ := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+		if !bytes.Equal(publicKey.(ed25519.PublicKey), expected) {
+			t.Fatal("Mismatched public key")
+		}
+		if kty := jwk.GetKeyTypeFromKey(privateKey); kty != jwa.OKP {
+			t.Fatalf("Expected key type OKP, got %s", kty)
+		}
+	})
+	t.Run("Thumbprint", func(t *testing.T) {
+		set, err := jwk.ParseString(exampleEd25519Key)
+		if err != nil {
+			t.Fatalf("Failed to parse JWK: %s", err.Error())
+		}
+		thumbprint, err := jwk.Thumbprint(set.Keys[0], crypto.SHA256)
+		if err != nil {
+			t.Fatalf("Failed to compute thumbprint: %s", err.Error())
+		}
+		// https://tools.ietf.org/html/rfc8037#appendix-A.3
+		if encoded := base64.RawURLEncoding.EncodeToString(thumbprint); encoded != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+			t.Fatalf("Unexpected thumbprint %s", encoded)
+		}
+	})
+	t.Run("RoundTrip", func(t *testing.T) {
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %s", err.Error())
+		}
+		for _, rawKey := range []interface{}{publicKey, privateKey} {
+			key, err := jwk.New(rawKey)
+			if err != nil {
+				t.Fatalf("Failed to create JWK: %s", err.Error())
+			}
+			buf, err := json.Marshal(key)
+			if err != nil {
+				t.Fatalf("Failed to marshal JWK: %s", err.Error())
+			}
+			set, err := jwk.ParseBytes(buf)
+			if err != nil {
+				t.Fatalf("Failed to parse JWK: %s", err.Error())
+			}
+			parsed, err := set.Keys[0].Materialize()
+			if err != nil {
+				t.Fatalf("Failed to materialize key: %s", err.Error())
+			}
+			if !reflect.DeepEqual(parsed, rawKey) {
+				t.Fatalf("Mismatched key after round trip: %s", buf)
+			}
+		}
+	})
+	t.Run("Key Generation Errors", func(t *testing.T) {
+		for _, src := range []string{
+			`{"kty":"OKP","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+			`{"kty":"OKP","crv":"X25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+			`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg"}`,
+			`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","d":"mWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A"}`,
+		} {
+			if _, err := jwk.ParseString(src); err == nil {
+				t.Fatalf("Parsing should fail for %s", src)
+			}
+		}
+	})
+}
@@ -1,9 +1,12 @@
 package jwk
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/mldsa"
 )
 
 // Set is a convenience struct to allow generating and parsing
@@ -22,11 +25,23 @@ type Key interface {
 	// Materialize creates the corresponding key. For example,
 	// RSA types would create *rsa.PublicKey or *rsa.PrivateKey,
 	// EC types would create *ecdsa.PublicKey or *ecdsa.PrivateKey,
+	// OKP types would create ed25519.PublicKey or ed25519.PrivateKey,
+	// AKP types would create *mldsa.PublicKey or *mldsa.PrivateKey,
 	// and OctetSeq types create a []byte key.
 	Materialize() (interface{}, error)
 	GenerateKey(*RawKeyJSON) error
 }
 
+// Thumbprinter is implemented by the keys whose JWK Thumbprint can be
+// computed, which includes all of the keys of this package. Use the
+// Thumbprint function rather than calling it directly.
+type Thumbprinter interface {
+	// Thumbprint computes the JWK Thumbprint of the key as described in
+	// https://tools.ietf.org/html/rfc7638, using the given hash function.
+	// The thumbprint of a private key is the one of its public key.
+	Thumbprint(crypto.Hash) ([]byte, error)
+}
+
 // RawKeyJSON is generic type that represents any kind JWK
 type RawKeyJSON struct {
 	StandardHeaders
@@ -67,3 +82,27 @@ type ECDSAPrivateKey struct {
 	*StandardHeaders
 	key *ecdsa.PrivateKey
 }
+
+// Ed25519PublicKey is a type of JWK generated from Ed25519 public keys
+type Ed25519PublicKey struct {
+	*StandardHeaders
+	key ed25519.PublicKey
+}
+
+// Ed25519PrivateKey is a type of JWK generated from Ed25519 private keys
+type Ed25519PrivateKey struct {
+	*StandardHeaders
+	key ed25519.PrivateKey
+}
+
+// MLDSAPublicKey is a type of JWK generated from ML-DSA public keys
+type MLDSAPublicKey struct {
+	*StandardHeaders
+	key *mldsa.PublicKey
+}
+
+// MLDSAPrivateKey is a type of JWK generated from ML-DSA private keys
+type MLDSAPrivateKey struct {
+	*StandardHeaders
+	key *mldsa.PrivateKey
+}
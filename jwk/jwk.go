@@ -3,14 +3,17 @@ package jwk
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"encoding/json"
 	"github.com/pkg/errors"
 	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/mldsa"
 )
 
 // GetPublicKey returns the public key based on the private key type.
 // For rsa key types *rsa.PublicKey is returned; for ecdsa key types *ecdsa.PublicKey;
+// for ed25519 key types ed25519.PublicKey; for mldsa key types *mldsa.PublicKey;
 // for byte slice (raw) keys, the key itself is returned. If the corresponding
 // public key cannot be deduced, an error is returned
 func GetPublicKey(key interface{}) (interface{}, error) {
@@ -27,6 +30,10 @@ func GetPublicKey(key interface{}) (interface{}, error) {
 		return v.Public(), nil
 	case *ecdsa.PrivateKey:
 		return v.Public(), nil
+	case ed25519.PrivateKey:
+		return v.Public(), nil
+	case *mldsa.PrivateKey:
+		return v.Public(), nil
 	case []byte:
 		return v, nil
 	default:
@@ -42,6 +49,10 @@ func GetKeyTypeFromKey(key interface{}) jwa.KeyType {
 		return jwa.RSA
 	case *ecdsa.PrivateKey, *ecdsa.PublicKey:
 		return jwa.EC
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwa.OKP
+	case *mldsa.PrivateKey, *mldsa.PublicKey:
+		return jwa.AKP
 	case []byte:
 		return jwa.OctetSeq
 	default:
@@ -64,6 +75,14 @@ func New(key interface{}) (Key, error) {
 		return newECDSAPrivateKey(v)
 	case *ecdsa.PublicKey:
 		return newECDSAPublicKey(v)
+	case ed25519.PrivateKey:
+		return newEd25519PrivateKey(v)
+	case ed25519.PublicKey:
+		return newEd25519PublicKey(v)
+	case *mldsa.PrivateKey:
+		return newMLDSAPrivateKey(v)
+	case *mldsa.PublicKey:
+		return newMLDSAPublicKey(v)
 	case []byte:
 		return newSymmetricKey(v)
 	default:
@@ -135,6 +154,18 @@ func (r *RawKeyJSON) GenerateKey() (Key, error) {
 		} else {
 			key = &ECDSAPublicKey{}
 		}
+	case jwa.OKP:
+		if r.D != nil {
+			key = &Ed25519PrivateKey{}
+		} else {
+			key = &Ed25519PublicKey{}
+		}
+	case jwa.AKP:
+		if r.Priv != nil {
+			key = &MLDSAPrivateKey{}
+		} else {
+			key = &MLDSAPublicKey{}
+		}
 	case jwa.OctetSeq:
 		key = &SymmetricKey{}
 	default:
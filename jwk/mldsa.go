@@ -0,0 +1,171 @@
+package jwk
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/mldsa"
+)
+
+// mldsaParameters maps the "alg" parameter of AKP keys to their ML-DSA
+// parameter set
+var mldsaParameters = map[jwa.SignatureAlgorithm]func() *mldsa.Parameters{
+	jwa.MLDSA44: mldsa.MLDSA44,
+	jwa.MLDSA65: mldsa.MLDSA65,
+	jwa.MLDSA87: mldsa.MLDSA87,
+}
+
+// newMLDSAHeaders creates the headers of AKP keys, which must declare
+// the algorithm they are used with
+func newMLDSAHeaders(params *mldsa.Parameters) (*StandardHeaders, error) {
+
+	var hdr StandardHeaders
+	err := hdr.Set(KeyTypeKey, jwa.AKP)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to set Key Type")
+	}
+	err = hdr.Set(AlgorithmKey, jwa.SignatureAlgorithm(params.Name()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to set Algorithm")
+	}
+	return &hdr, nil
+}
+
+func newMLDSAPublicKey(key *mldsa.PublicKey) (*MLDSAPublicKey, error) {
+
+	hdr, err := newMLDSAHeaders(key.Parameters())
+	if err != nil {
+		return nil, err
+	}
+
+	return &MLDSAPublicKey{
+		StandardHeaders: hdr,
+		key:             key,
+	}, nil
+}
+
+func newMLDSAPrivateKey(key *mldsa.PrivateKey) (*MLDSAPrivateKey, error) {
+
+	hdr, err := newMLDSAHeaders(key.Parameters())
+	if err != nil {
+		return nil, err
+	}
+
+	return &MLDSAPrivateKey{
+		StandardHeaders: hdr,
+		key:             key,
+	}, nil
+}
+
+// Materialize returns the ML-DSA public key represented by this JWK
+func (k MLDSAPublicKey) Materialize() (interface{}, error) {
+	return k.key, nil
+}
+
+// Materialize returns the ML-DSA private key represented by this JWK
+func (k MLDSAPrivateKey) Materialize() (interface{}, error) {
+	return k.key, nil
+}
+
+// GenerateKey creates a MLDSAPublicKey from JWK format. The "alg"
+// parameter selects the ML-DSA parameter set, and "pub" holds the
+// encoded public key.
+func (k *MLDSAPublicKey) GenerateKey(keyJSON *RawKeyJSON) error {
+
+	if keyJSON.Pub == nil || keyJSON.Algorithm == nil {
+		return errors.Errorf("Missing mandatory key parameters Pub or Alg")
+	}
+	params, ok := mldsaParameters[*keyJSON.Algorithm]
+	if !ok {
+		return errors.Errorf(`invalid algorithm %s for key type %s`, *keyJSON.Algorithm, jwa.AKP)
+	}
+	publicKey, err := mldsa.NewPublicKey(params(), keyJSON.Pub.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "Failed to generate public key. Incorrect Pub value")
+	}
+
+	*k = MLDSAPublicKey{
+		StandardHeaders: &keyJSON.StandardHeaders,
+		key:             publicKey,
+	}
+	return nil
+}
+
+// GenerateKey creates a MLDSAPrivateKey from JWK format. The "priv"
+// parameter holds the seed of the private key.
+func (k *MLDSAPrivateKey) GenerateKey(keyJSON *RawKeyJSON) error {
+
+	if keyJSON.Priv == nil {
+		return errors.Errorf("Missing mandatory key parameter Priv")
+	}
+	mldsaPublicKey := &MLDSAPublicKey{}
+	err := mldsaPublicKey.GenerateKey(keyJSON)
+	if err != nil {
+		return errors.Wrap(err, `failed to generate public key`)
+	}
+	privateKey, err := mldsa.NewPrivateKey(mldsaPublicKey.key.Parameters(), keyJSON.Priv.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "Failed to generate private key. Incorrect Priv value")
+	}
+	if !bytes.Equal(privateKey.PublicKey().Bytes(), keyJSON.Pub.Bytes()) {
+		return errors.Errorf("Failed to generate private key. Pub does not match Priv")
+	}
+
+	k.key = privateKey
+	k.StandardHeaders = &keyJSON.StandardHeaders
+
+	return nil
+}
+
+// MarshalJSON serializes the ML-DSA public key in JWK format
+func (k *MLDSAPublicKey) MarshalJSON() ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no *mldsa.PublicKey associated with it`)
+	}
+	raw := newRawKeyJSON(k.StandardHeaders, jwa.AKP)
+	alg := jwa.SignatureAlgorithm(k.key.Parameters().Name())
+	raw.Algorithm = &alg
+	raw.Pub = k.key.Bytes()
+	return json.Marshal(raw)
+}
+
+// MarshalJSON serializes the ML-DSA private key in JWK format
+func (k *MLDSAPrivateKey) MarshalJSON() ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no *mldsa.PrivateKey associated with it`)
+	}
+	raw := newRawKeyJSON(k.StandardHeaders, jwa.AKP)
+	alg := jwa.SignatureAlgorithm(k.key.Parameters().Name())
+	raw.Algorithm = &alg
+	raw.Pub = k.key.PublicKey().Bytes()
+	raw.Priv = k.key.Seed()
+	return json.Marshal(raw)
+}
+
+// Thumbprint returns the JWK Thumbprint of the ML-DSA public key
+func (k *MLDSAPublicKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no *mldsa.PublicKey associated with it`)
+	}
+	return mldsaThumbprint(hash, k.key)
+}
+
+// Thumbprint returns the JWK Thumbprint of the public part of the ML-DSA private key
+func (k *MLDSAPrivateKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no *mldsa.PrivateKey associated with it`)
+	}
+	return mldsaThumbprint(hash, k.key.PublicKey())
+}
+
+// mldsaThumbprint uses the "alg", "kty" and "pub" members, which are the
+// required members of AKP keys
+func mldsaThumbprint(hash crypto.Hash, key *mldsa.PublicKey) ([]byte, error) {
+	return thumbprint(hash, fmt.Sprintf(`{"alg":"%s","kty":"AKP","pub":"%s"}`,
+		key.Parameters().Name(), base64.RawURLEncoding.EncodeToString(key.Bytes())))
+}
@@ -0,0 +1,101 @@
+package jwk_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jwk"
+	"github.com/repenno/jwx-opa/mldsa"
+)
+
+func TestMLDSA(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x2a}, mldsa.SeedSize)
+	privateKey, err := mldsa.NewPrivateKey(mldsa.MLDSA44(), seed)
+	if err != nil {
+		t.Fatalf("Failed to create private key: %s", err.Error())
+	}
+	pub := base64.RawURLEncoding.EncodeToString(privateKey.PublicKey().Bytes())
+	priv := base64.RawURLEncoding.EncodeToString(seed)
+
+	t.Run("Round trip", func(t *testing.T) {
+		key, err := jwk.New(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		if key.GetKeyType() != jwa.AKP || key.GetAlgorithm() != jwa.MLDSA44 {
+			t.Fatalf("Expected an AKP key for ML-DSA-44, got %s for %s", key.GetKeyType(), key.GetAlgorithm())
+		}
+		buf, err := json.Marshal(key)
+		if err != nil {
+			t.Fatalf("Failed to marshal JWK: %s", err.Error())
+		}
+		var raw map[string]interface{}
+		if err := json.Unmarshal(buf, &raw); err != nil {
+			t.Fatalf("Failed to unmarshal JWK: %s", err.Error())
+		}
+		if raw["kty"] != "AKP" || raw["alg"] != "ML-DSA-44" || raw["pub"] != pub || raw["priv"] != priv {
+			t.Fatalf("Unexpected JWK members %s", buf)
+		}
+
+		set, err := jwk.ParseBytes(buf)
+		if err != nil {
+			t.Fatalf("Failed to parse JWK: %s", err.Error())
+		}
+		if _, ok := set.Keys[0].(*jwk.MLDSAPrivateKey); !ok {
+			t.Fatalf("Expected *jwk.MLDSAPrivateKey, got %T", set.Keys[0])
+		}
+		rawKey, err := set.Keys[0].Materialize()
+		if err != nil {
+			t.Fatalf("Failed to materialize key: %s", err.Error())
+		}
+		if parsed, ok := rawKey.(*mldsa.PrivateKey); !ok || !parsed.Equal(privateKey) {
+			t.Fatal("Mismatched key after round trip")
+		}
+	})
+	t.Run("Public key", func(t *testing.T) {
+		set, err := jwk.ParseString(fmt.Sprintf(`{"kty":"AKP","alg":"ML-DSA-44","pub":"%s"}`, pub))
+		if err != nil {
+			t.Fatalf("Failed to parse JWK: %s", err.Error())
+		}
+		rawKey, err := set.Keys[0].Materialize()
+		if err != nil {
+			t.Fatalf("Failed to materialize key: %s", err.Error())
+		}
+		if publicKey, ok := rawKey.(*mldsa.PublicKey); !ok || !publicKey.Equal(privateKey.Public()) {
+			t.Fatal("Mismatched public key")
+		}
+
+		thumbprint, err := jwk.Thumbprint(set.Keys[0], crypto.SHA256)
+		if err != nil {
+			t.Fatalf("Failed to compute thumbprint: %s", err.Error())
+		}
+		expected := sha256.Sum256([]byte(fmt.Sprintf(`{"alg":"ML-DSA-44","kty":"AKP","pub":"%s"}`, pub)))
+		if !bytes.Equal(thumbprint, expected[:]) {
+			t.Fatal("Mismatched thumbprint")
+		}
+	})
+	t.Run("Invalid keys", func(t *testing.T) {
+		otherKey, err := mldsa.NewPrivateKey(mldsa.MLDSA44(), make([]byte, mldsa.SeedSize))
+		if err != nil {
+			t.Fatalf("Failed to create private key: %s", err.Error())
+		}
+		otherPub := base64.RawURLEncoding.EncodeToString(otherKey.PublicKey().Bytes())
+		for name, jwkSrc := range map[string]string{
+			"Missing alg":        fmt.Sprintf(`{"kty":"AKP","pub":"%s"}`, pub),
+			"Unsupported alg":    fmt.Sprintf(`{"kty":"AKP","alg":"EdDSA","pub":"%s"}`, pub),
+			"Mismatched alg":     fmt.Sprintf(`{"kty":"AKP","alg":"ML-DSA-65","pub":"%s"}`, pub),
+			"Short seed":         fmt.Sprintf(`{"kty":"AKP","alg":"ML-DSA-44","pub":"%s","priv":"%s"}`, pub, priv[:20]),
+			"Pub does not match": fmt.Sprintf(`{"kty":"AKP","alg":"ML-DSA-44","pub":"%s","priv":"%s"}`, otherPub, priv),
+		} {
+			if _, err := jwk.ParseString(jwkSrc); err == nil {
+				t.Fatalf("%s: parsing should fail", name)
+			}
+		}
+	})
+}
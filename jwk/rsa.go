@@ -1,10 +1,14 @@
 package jwk
 
 import (
+	"crypto"
 	"crypto/rsa"
+	"encoding/json"
+	"fmt"
 	"math/big"
 
 	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/buffer"
 	"github.com/repenno/jwx-opa/jwa"
 )
 
@@ -100,3 +104,64 @@ func (k *RSAPrivateKey) GenerateKey(keyJSON *RawKeyJSON) error {
 	k.StandardHeaders = &keyJSON.StandardHeaders
 	return nil
 }
+
+// MarshalJSON serializes the RSA public key in JWK format
+func (k *RSAPublicKey) MarshalJSON() ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no rsa.PublicKey associated with it`)
+	}
+	raw := newRawKeyJSON(k.StandardHeaders, jwa.RSA)
+	raw.N = buffer.Buffer(k.key.N.Bytes())
+	raw.E = buffer.FromUint(uint64(k.key.E))
+	return json.Marshal(raw)
+}
+
+// MarshalJSON serializes the RSA private key in JWK format
+func (k *RSAPrivateKey) MarshalJSON() ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no rsa.PrivateKey associated with it`)
+	}
+	if len(k.key.Primes) != 2 {
+		return nil, errors.New(`RSA keys with more than two primes are not supported`)
+	}
+	raw := newRawKeyJSON(k.StandardHeaders, jwa.RSA)
+	raw.N = buffer.Buffer(k.key.N.Bytes())
+	raw.E = buffer.FromUint(uint64(k.key.E))
+	raw.D = buffer.Buffer(k.key.D.Bytes())
+	raw.P = buffer.Buffer(k.key.Primes[0].Bytes())
+	raw.Q = buffer.Buffer(k.key.Primes[1].Bytes())
+	if k.key.Precomputed.Dp != nil {
+		raw.Dp = buffer.Buffer(k.key.Precomputed.Dp.Bytes())
+	}
+	if k.key.Precomputed.Dq != nil {
+		raw.Dq = buffer.Buffer(k.key.Precomputed.Dq.Bytes())
+	}
+	if k.key.Precomputed.Qinv != nil {
+		raw.Qi = buffer.Buffer(k.key.Precomputed.Qinv.Bytes())
+	}
+	return json.Marshal(raw)
+}
+
+// Thumbprint returns the JWK Thumbprint of the RSA public key
+func (k *RSAPublicKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no rsa.PublicKey associated with it`)
+	}
+	return rsaThumbprint(hash, k.key)
+}
+
+// Thumbprint returns the JWK Thumbprint of the public part of the RSA private key
+func (k *RSAPrivateKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New(`key has no rsa.PrivateKey associated with it`)
+	}
+	return rsaThumbprint(hash, &k.key.PublicKey)
+}
+
+func rsaThumbprint(hash crypto.Hash, key *rsa.PublicKey) ([]byte, error) {
+	e := buffer.FromUint(uint64(key.E))
+	n := buffer.Buffer(key.N.Bytes())
+	eBuf, _ := e.Base64Encode()
+	nBuf, _ := n.Base64Encode()
+	return thumbprint(hash, fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, eBuf, nBuf))
+}
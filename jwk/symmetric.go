@@ -1,6 +1,11 @@
 package jwk
 
 import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
 	"github.com/pkg/errors"
 	"github.com/repenno/jwx-opa/jwa"
 )
@@ -38,3 +43,15 @@ func (s *SymmetricKey) GenerateKey(keyJSON *RawKeyJSON) error {
 	}
 	return nil
 }
+
+// MarshalJSON serializes the symmetric key in JWK format
+func (s *SymmetricKey) MarshalJSON() ([]byte, error) {
+	raw := newRawKeyJSON(s.StandardHeaders, jwa.OctetSeq)
+	raw.K = s.key
+	return json.Marshal(raw)
+}
+
+// Thumbprint returns the JWK Thumbprint of the symmetric key
+func (s *SymmetricKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+	return thumbprint(hash, fmt.Sprintf(`{"k":"%s","kty":"oct"}`, base64.RawURLEncoding.EncodeToString(s.key)))
+}
@@ -0,0 +1,64 @@
+package jwk
+
+import (
+	"crypto"
+	"encoding/base64"
+	"math/big"
+
+	// Register the hash functions commonly used for thumbprints
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+)
+
+// Thumbprint computes the JWK Thumbprint of `key` as described in
+// https://tools.ietf.org/html/rfc7638, using `hash`. The thumbprint of a
+// private key is the one of its public key. Keys that do not implement
+// Thumbprinter are rejected.
+func Thumbprint(key Key, hash crypto.Hash) ([]byte, error) {
+	t, ok := key.(Thumbprinter)
+	if !ok {
+		return nil, errors.Errorf(`key of type %T does not support thumbprints`, key)
+	}
+	return t.Thumbprint(hash)
+}
+
+// thumbprint hashes the JSON object made of the required members of a
+// key, as described in https://tools.ietf.org/html/rfc7638#section-3
+func thumbprint(hash crypto.Hash, members string) ([]byte, error) {
+	if !hash.Available() {
+		return nil, errors.Errorf(`hash function %v is not available`, hash)
+	}
+	h := hash.New()
+	h.Write([]byte(members))
+	return h.Sum(nil), nil
+}
+
+// newRawKeyJSON creates the JWK representation of a key of type `kty`,
+// which is then completed with the key parameters
+func newRawKeyJSON(hdr *StandardHeaders, kty jwa.KeyType) *RawKeyJSON {
+	raw := &RawKeyJSON{}
+	if hdr != nil {
+		raw.StandardHeaders = *hdr
+	}
+	raw.KeyType = kty
+	return raw
+}
+
+// encodeInt encodes a number using the base64url encoding of its
+// big-endian representation, padded to `size` octets
+func encodeInt(v *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(padBytes(v.Bytes(), size))
+}
+
+// padBytes left pads `b` with zeroes to `size` octets
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	out := make([]byte, size)
+	copy(out[size-len(b):], b)
+	return out
+}
@@ -0,0 +1,138 @@
+package jwk_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/repenno/jwx-opa/jwk"
+)
+
+func TestThumbprint(t *testing.T) {
+
+	// https://tools.ietf.org/html/rfc7638#section-3.1
+	t.Run("RFC7638 Example", func(t *testing.T) {
+		const jwkSrc = `{
+  "kty": "RSA",
+  "n": "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+  "e": "AQAB",
+  "alg": "RS256",
+  "kid": "2011-04-29"
+}`
+		set, err := jwk.ParseString(jwkSrc)
+		if err != nil {
+			t.Fatalf("Failed to parse JWK: %s", err.Error())
+		}
+		tp, err := jwk.Thumbprint(set.Keys[0], crypto.SHA256)
+		if err != nil {
+			t.Fatalf("Failed to compute thumbprint: %s", err.Error())
+		}
+		if got := base64.RawURLEncoding.EncodeToString(tp); got != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+			t.Fatalf("Mismatched thumbprint: %s", got)
+		}
+	})
+	t.Run("Private and public keys", func(t *testing.T) {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %s", err.Error())
+		}
+		ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %s", err.Error())
+		}
+		pairs := [][2]interface{}{
+			{rsaKey, &rsaKey.PublicKey},
+			{ecKey, &ecKey.PublicKey},
+		}
+		for _, pair := range pairs {
+			var thumbprints [][]byte
+			for _, key := range pair {
+				jwkKey, err := jwk.New(key)
+				if err != nil {
+					t.Fatalf("Failed to create JWK: %s", err.Error())
+				}
+				tp, err := jwk.Thumbprint(jwkKey, crypto.SHA256)
+				if err != nil {
+					t.Fatalf("Failed to compute thumbprint: %s", err.Error())
+				}
+				thumbprints = append(thumbprints, tp)
+			}
+			if !reflect.DeepEqual(thumbprints[0], thumbprints[1]) {
+				t.Fatalf("Thumbprints of %T and its public key differ", pair[0])
+			}
+		}
+	})
+	t.Run("Key without thumbprint", func(t *testing.T) {
+		jwkKey, err := jwk.New([]byte("Avracadabra"))
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		// Keys implemented outside of this package only need the methods
+		// of jwk.Key
+		if _, err := jwk.Thumbprint(customKey{jwkKey}, crypto.SHA256); err == nil {
+			t.Fatal("Thumbprint of a key that does not implement jwk.Thumbprinter should fail")
+		}
+	})
+}
+
+// customKey hides the methods of a key that are not part of jwk.Key
+type customKey struct {
+	jwk.Key
+}
+
+func TestMarshalJSON(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	keys := []interface{}{rsaKey, &rsaKey.PublicKey, ecKey, &ecKey.PublicKey, []byte("Avracadabra")}
+
+	for _, key := range keys {
+		jwkKey, err := jwk.New(key)
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		if err := jwkKey.Set(jwk.KeyIDKey, "my-key"); err != nil {
+			t.Fatalf("Failed to set kid: %s", err.Error())
+		}
+		buf, err := json.Marshal(jwkKey)
+		if err != nil {
+			t.Fatalf("Failed to marshal %T: %s", key, err.Error())
+		}
+		set, err := jwk.ParseBytes(buf)
+		if err != nil {
+			t.Fatalf("Failed to parse marshalled %T: %s", key, err.Error())
+		}
+		if set.Keys[0].GetKeyID() != "my-key" {
+			t.Fatalf("Headers of %T were not marshalled", key)
+		}
+		parsed, err := set.Keys[0].Materialize()
+		if err != nil {
+			t.Fatalf("Failed to materialize %T: %s", key, err.Error())
+		}
+		switch v := key.(type) {
+		case *rsa.PrivateKey:
+			if v.D.Cmp(parsed.(*rsa.PrivateKey).D) != 0 || v.N.Cmp(parsed.(*rsa.PrivateKey).N) != 0 {
+				t.Fatal("Mismatched RSA private key")
+			}
+		case *ecdsa.PrivateKey:
+			if v.D.Cmp(parsed.(*ecdsa.PrivateKey).D) != 0 || v.X.Cmp(parsed.(*ecdsa.PrivateKey).X) != 0 {
+				t.Fatal("Mismatched EC private key")
+			}
+		default:
+			if !reflect.DeepEqual(key, parsed) {
+				t.Fatalf("Mismatched %T", key)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package jws
+
+import (
+	"github.com/pkg/errors"
+)
+
+// CriticalHandler processes an extension header parameter listed in the
+// "crit" header parameter. It is given the protected Headers of the
+// signature being verified, and returns an error if the extension is
+// not satisfied.
+type CriticalHandler func(Headers) error
+
+// standardHeaderNames lists the header parameters defined by
+// https://tools.ietf.org/html/rfc7515#section-4.1, which must not be
+// listed in the "crit" header parameter
+var standardHeaderNames = map[string]struct{}{
+	"alg": {}, "jku": {}, "jwk": {}, "kid": {}, "x5u": {}, "x5c": {},
+	"x5t": {}, "x5t#S256": {}, "typ": {}, "cty": {}, "crit": {},
+}
+
+// supportedCritical lists the extension header parameters that are
+// understood by this package
+var supportedCritical = map[string]struct{}{
+	Base64Key: {},
+}
+
+// checkCritical enforces the "crit" header parameter as described in
+// https://tools.ietf.org/html/rfc7515#section-4.1.11
+func (cfg *verifyConfig) checkCritical(protected, unprotected Headers) error {
+
+	if unprotected != nil {
+		if _, ok := unprotected.Get(CriticalKey); ok {
+			return errors.Errorf(`%s header parameter must be protected`, CriticalKey)
+		}
+	}
+	if protected == nil {
+		return nil
+	}
+	v, ok := protected.Get(CriticalKey)
+	if !ok {
+		return nil
+	}
+	crit, ok := v.([]string)
+	if !ok {
+		return errors.Errorf(`invalid value for %s key: %T`, CriticalKey, v)
+	}
+	if len(crit) == 0 {
+		return errors.Errorf(`%s header parameter must not be empty`, CriticalKey)
+	}
+
+	for _, name := range crit {
+		if _, ok := standardHeaderNames[name]; ok {
+			return errors.Errorf(`standard header parameter %s must not be listed as critical`, name)
+		}
+		if _, ok := supportedCritical[name]; ok {
+			continue
+		}
+		handler, ok := cfg.criticalHandlers[name]
+		if !ok {
+			return errors.Errorf(`unsupported critical header parameter %s`, name)
+		}
+		if err := handler(protected); err != nil {
+			return errors.Wrapf(err, `critical header parameter %s rejected`, name)
+		}
+	}
+	return nil
+}
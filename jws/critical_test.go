@@ -0,0 +1,103 @@
+package jws_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jws"
+)
+
+func TestCritical(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+	sharedKey := []byte("Avracadabra")
+
+	sign := func(t *testing.T, hdr string) []byte {
+		t.Helper()
+		signed, err := jws.SignLiteral(payload, jwa.HS256, sharedKey, []byte(hdr))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		return signed
+	}
+
+	t.Run("Unknown extension", func(t *testing.T) {
+		signed := sign(t, `{"alg":"HS256","crit":["exp"],"exp":1363284000}`)
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey); err == nil {
+			t.Fatal("Verification with an unknown critical extension should fail")
+		}
+		if _, err := jws.ParseByte(signed); err != nil {
+			t.Fatalf("Parsing should not enforce critical extensions: %s", err.Error())
+		}
+	})
+	t.Run("Registered handler", func(t *testing.T) {
+		signed := sign(t, `{"alg":"HS256","crit":["exp"],"exp":1363284000}`)
+		var called bool
+		handler := func(hdr jws.Headers) error {
+			called = true
+			if exp, ok := hdr.Get("exp"); !ok || exp != float64(1363284000) {
+				return errors.New("missing exp header parameter")
+			}
+			return nil
+		}
+		verified, err := jws.Verify(signed, jwa.HS256, sharedKey, jws.WithCriticalHandler("exp", handler))
+		if err != nil {
+			t.Fatalf("Verification with a registered handler failed: %s", err.Error())
+		}
+		if !called {
+			t.Fatal("Critical handler was not called")
+		}
+		if string(verified) != string(payload) {
+			t.Fatal("Mismatched payload")
+		}
+	})
+	t.Run("Rejecting handler", func(t *testing.T) {
+		signed := sign(t, `{"alg":"HS256","crit":["exp"],"exp":1363284000}`)
+		handler := func(jws.Headers) error {
+			return errors.New("expired")
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey, jws.WithCriticalHandler("exp", handler)); err == nil {
+			t.Fatal("Verification should fail when the handler rejects the extension")
+		}
+	})
+	t.Run("Empty list", func(t *testing.T) {
+		signed := sign(t, `{"alg":"HS256","crit":[]}`)
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey); err == nil {
+			t.Fatal("Verification with an empty critical list should fail")
+		}
+	})
+	t.Run("Standard header", func(t *testing.T) {
+		signed := sign(t, `{"alg":"HS256","crit":["alg"]}`)
+		handler := func(jws.Headers) error {
+			return nil
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey, jws.WithCriticalHandler("alg", handler)); err == nil {
+			t.Fatal("Verification with a standard header listed as critical should fail")
+		}
+	})
+	t.Run("Unprotected crit", func(t *testing.T) {
+		var unprotected jws.StandardHeaders
+		if err := unprotected.Set(jws.CriticalKey, []string{"exp"}); err != nil {
+			t.Fatalf("Failed to set crit: %s", err.Error())
+		}
+		signed, err := jws.SignFlattened(payload, jwa.HS256, sharedKey, nil, &unprotected)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		handler := func(jws.Headers) error {
+			return nil
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey, jws.WithCriticalHandler("exp", handler)); err == nil {
+			t.Fatal("Verification with an unprotected critical list should fail")
+		}
+	})
+	t.Run("Detached", func(t *testing.T) {
+		signed, err := jws.SignDetached(payload, jwa.HS256, sharedKey, []byte(`{"alg":"HS256","crit":["exp"]}`))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if err := jws.VerifyDetached(signed, payload, jwa.HS256, sharedKey); err == nil {
+			t.Fatal("Verification with an unknown critical extension should fail")
+		}
+	})
+}
@@ -1,20 +1,32 @@
 package jws
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"sort"
+
 	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/buffer"
 	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jwk"
 )
 
 // Constants for JWS Common parameters
 const (
-	AlgorithmKey     = "alg"
-	ContentTypeKey   = "cty"
-	CriticalKey      = "crit"
-	JWKKey           = "jwk"
-	JWKSetURLKey     = "jku"
-	KeyIDKey         = "kid"
-	PrivateParamsKey = "privateParams"
-	TypeKey          = "typ"
+	AlgorithmKey              = "alg"
+	Base64Key                 = "b64"
+	ContentTypeKey            = "cty"
+	CriticalKey               = "crit"
+	JWKKey                    = "jwk"
+	JWKSetURLKey              = "jku"
+	KeyIDKey                  = "kid"
+	PrivateParamsKey          = "privateParams"
+	TypeKey                   = "typ"
+	X509CertChainKey          = "x5c"
+	X509CertThumbprintKey     = "x5t"
+	X509CertThumbprintS256Key = "x5t#S256"
+	X509URLKey                = "x5u"
 )
 
 // Headers provides a common interface for common header parameters
@@ -26,16 +38,33 @@ type Headers interface {
 
 // StandardHeaders contains JWS common parameters.
 type StandardHeaders struct {
-	Algorithm     jwa.SignatureAlgorithm `json:"alg,omitempty"`           // https://tools.ietf.org/html/rfc7515#section-4.1.1
-	ContentType   string                 `json:"cty,omitempty"`           // https://tools.ietf.org/html/rfc7515#section-4.1.10
-	Critical      []string               `json:"crit,omitempty"`          // https://tools.ietf.org/html/rfc7515#section-4.1.11
-	JWK           string                 `json:"jwk,omitempty"`           // https://tools.ietf.org/html/rfc7515#section-4.1.3
-	JWKSetURL     string                 `json:"jku,omitempty"`           // https://tools.ietf.org/html/rfc7515#section-4.1.2
-	KeyID         string                 `json:"kid,omitempty"`           // https://tools.ietf.org/html/rfc7515#section-4.1.4
-	PrivateParams map[string]interface{} `json:"privateParams,omitempty"` // https://tools.ietf.org/html/rfc7515#section-4.1.9
-	Type          string                 `json:"typ,omitempty"`           // https://tools.ietf.org/html/rfc7515#section-4.1.9
+	Algorithm              jwa.SignatureAlgorithm `json:"alg,omitempty"`      // https://tools.ietf.org/html/rfc7515#section-4.1.1
+	Base64                 *bool                  `json:"b64,omitempty"`      // https://tools.ietf.org/html/rfc7797#section-3
+	ContentType            string                 `json:"cty,omitempty"`      // https://tools.ietf.org/html/rfc7515#section-4.1.10
+	Critical               []string               `json:"crit,omitempty"`     // https://tools.ietf.org/html/rfc7515#section-4.1.11
+	JWK                    jwk.Key                `json:"jwk,omitempty"`      // https://tools.ietf.org/html/rfc7515#section-4.1.3
+	JWKSetURL              string                 `json:"jku,omitempty"`      // https://tools.ietf.org/html/rfc7515#section-4.1.2
+	KeyID                  string                 `json:"kid,omitempty"`      // https://tools.ietf.org/html/rfc7515#section-4.1.4
+	PrivateParams          map[string]interface{} `json:"-"`                  // https://tools.ietf.org/html/rfc7515#section-4.3
+	Type                   string                 `json:"typ,omitempty"`      // https://tools.ietf.org/html/rfc7515#section-4.1.9
+	X509CertChain          CertificateChain       `json:"x5c,omitempty"`      // https://tools.ietf.org/html/rfc7515#section-4.1.6
+	X509CertThumbprint     buffer.Buffer          `json:"x5t,omitempty"`      // https://tools.ietf.org/html/rfc7515#section-4.1.7
+	X509CertThumbprintS256 buffer.Buffer          `json:"x5t#S256,omitempty"` // https://tools.ietf.org/html/rfc7515#section-4.1.8
+	X509URL                string                 `json:"x5u,omitempty"`      // https://tools.ietf.org/html/rfc7515#section-4.1.5
+}
+
+// registeredHeaderNames lists the header parameters held by the fields of
+// StandardHeaders. Any other header parameter is kept in PrivateParams.
+var registeredHeaderNames = map[string]struct{}{
+	AlgorithmKey: {}, Base64Key: {}, ContentTypeKey: {}, CriticalKey: {},
+	JWKKey: {}, JWKSetURLKey: {}, KeyIDKey: {}, TypeKey: {},
+	X509CertChainKey: {}, X509CertThumbprintKey: {}, X509CertThumbprintS256Key: {}, X509URLKey: {},
 }
 
+// standardHeadersJSON is used to (un)marshal the registered header
+// parameters without recursing into the methods of StandardHeaders
+type standardHeadersJSON StandardHeaders
+
 // GetAlgorithm returns algorithm
 func (h *StandardHeaders) GetAlgorithm() jwa.SignatureAlgorithm {
 	return h.Algorithm
@@ -50,6 +79,12 @@ func (h *StandardHeaders) Get(name string) (interface{}, bool) {
 			return nil, false
 		}
 		return v, true
+	case Base64Key:
+		v := h.Base64
+		if v == nil {
+			return nil, false
+		}
+		return *v, true
 	case ContentTypeKey:
 		v := h.ContentType
 		if v == "" {
@@ -57,14 +92,15 @@ func (h *StandardHeaders) Get(name string) (interface{}, bool) {
 		}
 		return v, true
 	case CriticalKey:
+		// An empty list is reported, as it is not the same as no list at all
 		v := h.Critical
-		if len(v) == 0 {
+		if v == nil {
 			return nil, false
 		}
 		return v, true
 	case JWKKey:
 		v := h.JWK
-		if v == "" {
+		if v == nil {
 			return nil, false
 		}
 		return v, true
@@ -92,8 +128,33 @@ func (h *StandardHeaders) Get(name string) (interface{}, bool) {
 			return nil, false
 		}
 		return v, true
+	case X509CertChainKey:
+		v := h.X509CertChain
+		if len(v) == 0 {
+			return nil, false
+		}
+		return v, true
+	case X509CertThumbprintKey:
+		v := h.X509CertThumbprint
+		if v.Len() == 0 {
+			return nil, false
+		}
+		return v.Bytes(), true
+	case X509CertThumbprintS256Key:
+		v := h.X509CertThumbprintS256
+		if v.Len() == 0 {
+			return nil, false
+		}
+		return v.Bytes(), true
+	case X509URLKey:
+		v := h.X509URL
+		if v == "" {
+			return nil, false
+		}
+		return v, true
 	default:
-		return nil, false
+		v, ok := h.PrivateParams[name]
+		return v, ok
 	}
 }
 
@@ -105,6 +166,12 @@ func (h *StandardHeaders) Set(name string, value interface{}) error {
 			return errors.Wrapf(err, `invalid value for %s key`, AlgorithmKey)
 		}
 		return nil
+	case Base64Key:
+		if v, ok := value.(bool); ok {
+			h.Base64 = &v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, Base64Key, value)
 	case ContentTypeKey:
 		if v, ok := value.(string); ok {
 			h.ContentType = v
@@ -118,7 +185,10 @@ func (h *StandardHeaders) Set(name string, value interface{}) error {
 		}
 		return errors.Errorf(`invalid value for %s key: %T`, CriticalKey, value)
 	case JWKKey:
-		if v, ok := value.(string); ok {
+		if v, ok := value.(jwk.Key); ok {
+			if err := checkPublicJWK(v); err != nil {
+				return errors.Wrapf(err, `invalid value for %s key`, JWKKey)
+			}
 			h.JWK = v
 			return nil
 		}
@@ -147,7 +217,158 @@ func (h *StandardHeaders) Set(name string, value interface{}) error {
 			return nil
 		}
 		return errors.Errorf(`invalid value for %s key: %T`, TypeKey, value)
+	case X509CertChainKey:
+		switch v := value.(type) {
+		case CertificateChain:
+			h.X509CertChain = v
+			return nil
+		case []*x509.Certificate:
+			h.X509CertChain = v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, X509CertChainKey, value)
+	case X509CertThumbprintKey:
+		if v, ok := value.([]byte); ok {
+			h.X509CertThumbprint = v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, X509CertThumbprintKey, value)
+	case X509CertThumbprintS256Key:
+		if v, ok := value.([]byte); ok {
+			h.X509CertThumbprintS256 = v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, X509CertThumbprintS256Key, value)
+	case X509URLKey:
+		if v, ok := value.(string); ok {
+			h.X509URL = v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, X509URLKey, value)
 	default:
-		return errors.Errorf(`invalid key: %s`, name)
+		if h.PrivateParams == nil {
+			h.PrivateParams = map[string]interface{}{}
+		}
+		h.PrivateParams[name] = value
+		return nil
+	}
+}
+
+// MarshalJSON serializes the Headers, writing the private header
+// parameters as top-level members after the registered ones
+func (h StandardHeaders) MarshalJSON() ([]byte, error) {
+	if h.JWK != nil {
+		if err := checkPublicJWK(h.JWK); err != nil {
+			return nil, errors.Wrapf(err, `invalid value for %s key`, JWKKey)
+		}
+	}
+	buf, err := json.Marshal(standardHeadersJSON(h))
+	if err != nil {
+		return nil, err
+	}
+	if len(h.PrivateParams) == 0 {
+		return buf, nil
+	}
+
+	names := make([]string, 0, len(h.PrivateParams))
+	for name := range h.PrivateParams {
+		if _, ok := registeredHeaderNames[name]; ok {
+			return nil, errors.Errorf(`private header parameter %s conflicts with a registered one`, name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := bytes.NewBuffer(buf[:len(buf)-1])
+	for _, name := range names {
+		value, err := json.Marshal(h.PrivateParams[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to marshal %s header parameter`, name)
+		}
+		if out.Len() > 1 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// UnmarshalJSON parses the Headers, keeping the members that are not
+// registered header parameters in PrivateParams
+func (h *StandardHeaders) UnmarshalJSON(buf []byte) error {
+	var hdr standardHeadersJSON
+	var embedded struct {
+		JWK json.RawMessage `json:"jwk,omitempty"`
+	}
+	if err := json.Unmarshal(buf, &embedded); err != nil {
+		return err
+	}
+	if len(embedded.JWK) > 0 {
+		key, err := parseEmbeddedJWK(embedded.JWK)
+		if err != nil {
+			return errors.Wrapf(err, `invalid value for %s key`, JWKKey)
+		}
+		hdr.JWK = key
+	}
+
+	// The embedded key has been parsed already, and must not be
+	// unmarshalled into the jwk.Key interface
+	var registered struct {
+		*standardHeadersJSON
+		JWK json.RawMessage `json:"jwk,omitempty"`
+	}
+	registered.standardHeadersJSON = &hdr
+	if err := json.Unmarshal(buf, &registered); err != nil {
+		return err
+	}
+	var members map[string]interface{}
+	if err := json.Unmarshal(buf, &members); err != nil {
+		return err
+	}
+	for name, value := range members {
+		if _, ok := registeredHeaderNames[name]; ok {
+			continue
+		}
+		if hdr.PrivateParams == nil {
+			hdr.PrivateParams = map[string]interface{}{}
+		}
+		hdr.PrivateParams[name] = value
+	}
+	*h = StandardHeaders(hdr)
+	return nil
+}
+
+// checkPublicJWK makes sure that the key of the "jwk" header parameter is
+// a public key, so that no private or secret key material is disclosed
+// in the Headers
+func checkPublicJWK(key jwk.Key) error {
+	keyVal, err := key.Materialize()
+	if err != nil {
+		return errors.Wrap(err, `failed to materialize key`)
+	}
+	if !isPublicKey(keyVal) {
+		return errors.New(`key must be a public key`)
+	}
+	return nil
+}
+
+// parseEmbeddedJWK parses the key found in the "jwk" header parameter,
+// which must be a single JWK rather than a JWK Set
+func parseEmbeddedJWK(buf []byte) (jwk.Key, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &members); err != nil {
+		return nil, errors.Wrap(err, `JWK must be a JSON object`)
+	}
+	if _, ok := members["keys"]; ok {
+		return nil, errors.New(`JWK Set is not allowed`)
+	}
+	set, err := jwk.ParseBytes(buf)
+	if err != nil {
+		return nil, err
 	}
+	return set.Keys[0], nil
 }
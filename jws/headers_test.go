@@ -1,9 +1,16 @@
 package jws_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jwk"
 	"github.com/repenno/jwx-opa/jws"
+	"github.com/repenno/jwx-opa/mldsa"
 	"reflect"
 	"testing"
 )
@@ -17,17 +24,28 @@ func TestHeader(t *testing.T) {
   "kid": "2011-04-29"
 }`
 
+	jwkSet, err := jwk.ParseString(jwkSrc)
+	if err != nil {
+		t.Fatalf("Failed to parse JWK: %s", err.Error())
+	}
 	privateHeaderParams := map[string]interface{}{"one": "1", "two": "11"}
+	cert, _ := generateCertificate(t, "Signer", false, nil, nil)
 
 	values := map[string]interface{}{
 		jws.AlgorithmKey:     jwa.ES256,
+		jws.Base64Key:        false,
 		jws.ContentTypeKey:   "example",
 		jws.CriticalKey:      []string{"exp"},
-		jws.JWKKey:           jwkSrc,
+		jws.JWKKey:           jwkSet.Keys[0],
 		jws.JWKSetURLKey:     "https://www.jwk.com/key.json",
 		jws.TypeKey:          "JWT",
 		jws.KeyIDKey:         "e9bc097a-ce51-4036-9562-d2ade882db0d",
 		jws.PrivateParamsKey: privateHeaderParams,
+
+		jws.X509CertChainKey:          jws.CertificateChain{cert},
+		jws.X509CertThumbprintKey:     []byte("thumbprint"),
+		jws.X509CertThumbprintS256Key: []byte("thumbprint#S256"),
+		jws.X509URLKey:                "https://www.x509.com/chain.pem",
 	}
 	t.Run("RoundTrip", func(t *testing.T) {
 
@@ -71,6 +89,18 @@ func TestHeader(t *testing.T) {
 		if err != nil {
 			t.Fatal("Failed to JSON marshal")
 		}
+		if hNew.JWK == nil || hNew.JWK.GetKeyID() != "2011-04-29" {
+			t.Fatal("Embedded JWK was not unmarshalled")
+		}
+	})
+	t.Run("Embedded JWK Set", func(t *testing.T) {
+
+		headers := `{"alg":"RS256","jwk":{"keys":[` + jwkSrc + `]}}`
+		var standardHeaders jws.StandardHeaders
+		err := json.Unmarshal([]byte(headers), &standardHeaders)
+		if err == nil {
+			t.Fatal("Unmarshal should have failed")
+		}
 	})
 	t.Run("RoundTripError", func(t *testing.T) {
 
@@ -82,6 +112,7 @@ func TestHeader(t *testing.T) {
 
 		values := map[string]interface{}{
 			jws.AlgorithmKey:     dummy,
+			jws.Base64Key:        dummy,
 			jws.ContentTypeKey:   dummy,
 			jws.CriticalKey:      dummy,
 			jws.JWKKey:           dummy,
@@ -89,7 +120,11 @@ func TestHeader(t *testing.T) {
 			jws.KeyIDKey:         dummy,
 			jws.TypeKey:          dummy,
 			jws.PrivateParamsKey: dummy,
-			"invalid key":        "",
+
+			jws.X509CertChainKey:          dummy,
+			jws.X509CertThumbprintKey:     dummy,
+			jws.X509CertThumbprintS256Key: dummy,
+			jws.X509URLKey:                dummy,
 		}
 
 		var h jws.StandardHeaders
@@ -106,6 +141,50 @@ func TestHeader(t *testing.T) {
 			}
 		}
 	})
+	t.Run("Private parameters", func(t *testing.T) {
+
+		var h jws.StandardHeaders
+		if err := h.Set(jws.AlgorithmKey, jwa.HS256); err != nil {
+			t.Fatalf("Set failed for %s", jws.AlgorithmKey)
+		}
+		if err := h.Set("x-request-id", "f81d4fae"); err != nil {
+			t.Fatal("Set failed for x-request-id")
+		}
+		if got, ok := h.Get("x-request-id"); !ok || got != "f81d4fae" {
+			t.Fatalf("Get failed for x-request-id: %v", got)
+		}
+		hByte, err := json.Marshal(h)
+		if err != nil {
+			t.Fatal("Failed to JSON marshal")
+		}
+		if expected := `{"alg":"HS256","x-request-id":"f81d4fae"}`; string(hByte) != expected {
+			t.Fatalf("Private parameters should be top-level members: %s", hByte)
+		}
+
+		src := `{"alg":"ES256","iat":1363284000,"nonce":"n-0S6_WzA2Mj","url":"https://example.com/acme/new-reg"}`
+		var hNew jws.StandardHeaders
+		if err := json.Unmarshal([]byte(src), &hNew); err != nil {
+			t.Fatalf("Failed to JSON unmarshal: %s", err.Error())
+		}
+		if hNew.GetAlgorithm() != jwa.ES256 {
+			t.Fatal("Registered parameters should not be lost")
+		}
+		expected := map[string]interface{}{"iat": float64(1363284000), "nonce": "n-0S6_WzA2Mj", "url": "https://example.com/acme/new-reg"}
+		if !reflect.DeepEqual(hNew.PrivateParams, expected) {
+			t.Fatalf("Values do not match: (%v, %v)", hNew.PrivateParams, expected)
+		}
+		if hByte, err = json.Marshal(&hNew); err != nil {
+			t.Fatal("Failed to JSON marshal")
+		}
+		if string(hByte) != src {
+			t.Fatalf("Headers do not round trip: %s", hByte)
+		}
+
+		hNew.PrivateParams[jws.KeyIDKey] = "conflict"
+		if _, err := json.Marshal(hNew); err == nil {
+			t.Fatal("Private parameters conflicting with registered ones should fail")
+		}
+	})
 	t.Run("Unknown alg", func(t *testing.T) {
 
 		headers := `{"typ":"JWT",` + "\r\n" + ` "alg":"dummy"}`
@@ -117,3 +196,92 @@ func TestHeader(t *testing.T) {
 
 	})
 }
+
+func TestHeaderPrivateJWK(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	mldsaKey, err := mldsa.GenerateKey(mldsa.MLDSA44(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+
+	// Members holding private or secret key material
+	secretMembers := []string{"d", "p", "q", "dp", "dq", "qi", "k", "priv"}
+	tests := []struct {
+		name       string
+		privateKey interface{}
+		publicKey  interface{}
+	}{
+		{name: "RSA", privateKey: rsaKey, publicKey: &rsaKey.PublicKey},
+		{name: "EC", privateKey: ecKey, publicKey: &ecKey.PublicKey},
+		{name: "Ed25519", privateKey: edKey, publicKey: edKey.Public()},
+		{name: "ML-DSA", privateKey: mldsaKey, publicKey: mldsaKey.Public()},
+		{name: "Symmetric", privateKey: []byte("Avracadabra")},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			privateJWK, err := jwk.New(test.privateKey)
+			if err != nil {
+				t.Fatalf("Failed to create JWK: %s", err.Error())
+			}
+			var h jws.StandardHeaders
+			if err := h.Set(jws.JWKKey, privateJWK); err == nil {
+				t.Fatal("Setting a private key should fail")
+			}
+			h.JWK = privateJWK
+			if buf, err := json.Marshal(h); err == nil {
+				t.Fatalf("Marshaling a private key should fail: %s", buf)
+			}
+			if signed, err := jws.SignWithHeaders(payload, jwa.HS256, []byte("secret"), &h); err == nil {
+				t.Fatalf("Signing with a private key in the Headers should fail: %s", signed)
+			}
+			if test.publicKey == nil {
+				return
+			}
+
+			publicJWK, err := jwk.New(test.publicKey)
+			if err != nil {
+				t.Fatalf("Failed to create JWK: %s", err.Error())
+			}
+			h = jws.StandardHeaders{}
+			if err := h.Set(jws.JWKKey, publicJWK); err != nil {
+				t.Fatalf("Failed to set public key: %s", err.Error())
+			}
+			signed, err := jws.SignWithHeaders(payload, jwa.HS256, []byte("secret"), &h)
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			msg, err := jws.ParseByte(signed)
+			if err != nil {
+				t.Fatalf("Failed to parse message: %s", err.Error())
+			}
+			var members struct {
+				JWK map[string]interface{} `json:"jwk"`
+			}
+			if err := json.Unmarshal(msg.GetSignatures()[0].RawProtectedHeaders(), &members); err != nil {
+				t.Fatalf("Failed to unmarshal Headers: %s", err.Error())
+			}
+			if len(members.JWK) == 0 {
+				t.Fatal("Missing jwk header parameter")
+			}
+			for _, name := range secretMembers {
+				if _, ok := members.JWK[name]; ok {
+					t.Fatalf("Serialized Headers disclose the %s member", name)
+				}
+			}
+		})
+	}
+}
@@ -8,15 +8,21 @@ package jws
 // signed payloads with. You should only use this when you want to actually
 // want to programmatically view the contents for the full JWS Payload.
 //
-// To sign and verify, use the appropriate `SignWithOption()` nad `Verify()` functions
+// To sign and verify, use the appropriate `SignWithOption()` nad `Verify()` functions.
+// The Message is marshaled to and from the general JSON serialization, and
+// can be serialized in compact form using `Compact()`.
 type Message struct {
 	Payload    []byte       `json:"payload"`
 	Signatures []*Signature `json:"signatures,omitempty"`
+
+	verified *verifyCandidate // Algorithm and key that verified the message
 }
 
 // Signature represents the headers and signature of a JWS message
 type Signature struct {
 	Headers   Headers `json:"header,omitempty"`    // Unprotected Headers
-	Protected Headers `json:"Protected,omitempty"` // Protected Headers
+	Protected Headers `json:"protected,omitempty"` // Protected Headers
 	Signature []byte  `json:"signature,omitempty"` // GetSignature
+
+	rawProtected []byte // Protected Headers exactly as they were signed
 }
@@ -0,0 +1,281 @@
+package jws
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// rawSignatureJSON is the representation of a single signature in the
+// JWS JSON serialization format, as described in
+// https://tools.ietf.org/html/rfc7515#section-7.2.1
+type rawSignatureJSON struct {
+	Protected string          `json:"protected,omitempty"`
+	Header    json.RawMessage `json:"header,omitempty"`
+	Signature *string         `json:"signature"`
+}
+
+// rawMessageJSON is the representation of a JWS message serialized
+// using the general JWS JSON serialization format
+type rawMessageJSON struct {
+	Payload    json.RawMessage     `json:"payload,omitempty"`
+	Signatures []*rawSignatureJSON `json:"signatures"`
+}
+
+// rawFlattenedJSON is the representation of a JWS message serialized
+// using the flattened JWS JSON serialization format, as described in
+// https://tools.ietf.org/html/rfc7515#section-7.2.2
+type rawFlattenedJSON struct {
+	Payload json.RawMessage `json:"payload,omitempty"`
+	rawSignatureJSON
+}
+
+// rawJSON is used to parse either of the JSON serialization formats
+type rawJSON struct {
+	rawFlattenedJSON
+	Signatures []*rawSignatureJSON `json:"signatures"`
+}
+
+// ParseJSON parses a JWS value serialized via either the general or the
+// flattened JSON serialization. The protected headers of each signature
+// are kept exactly as they were received, so that the message can be
+// verified or serialized again.
+func ParseJSON(buf []byte, options ...ParseOption) (*Message, error) {
+	return parseJSON(buf, newParseConfig(options))
+}
+
+func parseJSON(buf []byte, cfg *parseConfig) (*Message, error) {
+
+	if err := checkSize(`message`, len(buf), cfg.maxSize); err != nil {
+		return nil, err
+	}
+	var raw rawJSON
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal JSON serialization`)
+	}
+
+	rawSignatures := raw.Signatures
+	if rawSignatures == nil {
+		// No "signatures" member, so this must be the flattened form
+		if raw.Signature == nil {
+			return nil, errors.New(`missing signature in JSON serialization`)
+		}
+		rawSignatures = []*rawSignatureJSON{&raw.rawSignatureJSON}
+	} else if raw.Signature != nil || raw.Protected != "" || raw.Header != nil {
+		return nil, errors.New(`general JSON serialization must not contain flattened members`)
+	}
+	if len(rawSignatures) == 0 {
+		return nil, errors.New(`missing signatures in JSON serialization`)
+	}
+
+	var msg Message
+	for i, rawSig := range rawSignatures {
+		sig, err := parseSignatureJSON(rawSig, cfg)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to parse signature #%d`, i)
+		}
+		msg.Signatures = append(msg.Signatures, sig)
+	}
+
+	b64, err := msg.isBase64Payload()
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid Payload encoding`)
+	}
+	// Reject oversized Payloads before decoding them
+	if err := checkSize(`Payload`, jsonPayloadSize(raw.Payload, b64), cfg.maxPayloadSize); err != nil {
+		return nil, err
+	}
+	if msg.Payload, err = decodeJSONPayload(raw.Payload, b64); err != nil {
+		return nil, err
+	}
+	if err := checkSize(`Payload`, len(msg.Payload), cfg.maxPayloadSize); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// parseSignatureJSON creates a Signature from its JSON representation
+func parseSignatureJSON(rawSig *rawSignatureJSON, cfg *parseConfig) (*Signature, error) {
+
+	if rawSig.Protected == "" && len(rawSig.Header) == 0 {
+		return nil, errors.New(`missing both protected and unprotected Headers`)
+	}
+	// Reject oversized Headers before decoding them
+	if err := checkSize(`protected Headers`, base64.RawURLEncoding.DecodedLen(len(rawSig.Protected)), cfg.maxHeaderSize); err != nil {
+		return nil, err
+	}
+	if err := checkSize(`unprotected Headers`, len(rawSig.Header), cfg.maxHeaderSize); err != nil {
+		return nil, err
+	}
+
+	var sig Signature
+	if rawSig.Signature != nil {
+		signature, err := base64.RawURLEncoding.DecodeString(*rawSig.Signature)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to decode signature`)
+		}
+		sig.Signature = signature
+	}
+
+	if rawSig.Protected != "" {
+		protected, err := base64.RawURLEncoding.DecodeString(rawSig.Protected)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to decode protected Headers`)
+		}
+		var hdr StandardHeaders
+		if err := json.Unmarshal(protected, &hdr); err != nil {
+			return nil, errors.Wrap(err, `failed to parse protected Headers`)
+		}
+		sig.Protected = &hdr
+		sig.rawProtected = protected
+	}
+
+	if len(rawSig.Header) > 0 {
+		var hdr StandardHeaders
+		if err := json.Unmarshal(rawSig.Header, &hdr); err != nil {
+			return nil, errors.Wrap(err, `failed to parse unprotected Headers`)
+		}
+		if err := checkDisjointHeaders(sig.rawProtected, rawSig.Header); err != nil {
+			return nil, err
+		}
+		if _, ok := hdr.Get(Base64Key); ok {
+			return nil, errors.Errorf(`%s header parameter must be protected`, Base64Key)
+		}
+		sig.Headers = &hdr
+	}
+	return &sig, nil
+}
+
+// checkDisjointHeaders makes sure that no header parameter is present in both
+// the protected and the unprotected Headers, as required by
+// https://tools.ietf.org/html/rfc7515#section-7.2.1
+func checkDisjointHeaders(protected, unprotected []byte) error {
+
+	if len(protected) == 0 || len(unprotected) == 0 {
+		return nil
+	}
+	var protectedNames, unprotectedNames map[string]json.RawMessage
+	if err := json.Unmarshal(protected, &protectedNames); err != nil {
+		return errors.Wrap(err, `failed to parse protected Headers`)
+	}
+	if err := json.Unmarshal(unprotected, &unprotectedNames); err != nil {
+		return errors.Wrap(err, `failed to parse unprotected Headers`)
+	}
+	for name := range unprotectedNames {
+		if _, ok := protectedNames[name]; ok {
+			return errors.Errorf(`header %s is both protected and unprotected`, name)
+		}
+	}
+	return nil
+}
+
+// SerializeJSON serializes a JWS message using the general JSON serialization.
+// Protected Headers that were parsed or signed are emitted exactly as they
+// were received, otherwise they are marshaled from the Protected field.
+func SerializeJSON(m *Message) ([]byte, error) {
+
+	if m == nil || len(m.Signatures) == 0 {
+		return nil, errors.New(`message has no signatures to serialize`)
+	}
+
+	payload, err := m.encodeJSONPayload()
+	if err != nil {
+		return nil, err
+	}
+	raw := rawMessageJSON{
+		Payload: payload,
+	}
+	for i, sig := range m.Signatures {
+		rawSig, err := sig.toJSON()
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to serialize signature #%d`, i)
+		}
+		raw.Signatures = append(raw.Signatures, rawSig)
+	}
+	return json.Marshal(raw)
+}
+
+// SerializeFlattened serializes a JWS message with exactly one signature
+// using the flattened JSON serialization.
+func SerializeFlattened(m *Message) ([]byte, error) {
+
+	if m == nil || len(m.Signatures) != 1 {
+		return nil, errors.New(`flattened serialization requires exactly one signature`)
+	}
+
+	payload, err := m.encodeJSONPayload()
+	if err != nil {
+		return nil, err
+	}
+	rawSig, err := m.Signatures[0].toJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to serialize signature`)
+	}
+	return json.Marshal(rawFlattenedJSON{
+		Payload:          payload,
+		rawSignatureJSON: *rawSig,
+	})
+}
+
+// encodeJSONPayload returns the representation of the message Payload used
+// in the JSON serialization formats
+func (m *Message) encodeJSONPayload() (json.RawMessage, error) {
+	b64, err := m.isBase64Payload()
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid Payload encoding`)
+	}
+	return encodeJSONPayload(m.Payload, b64)
+}
+
+// toJSON creates the JSON representation of a Signature
+func (s *Signature) toJSON() (*rawSignatureJSON, error) {
+
+	protected, err := s.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	signature := base64.RawURLEncoding.EncodeToString(s.Signature)
+	rawSig := &rawSignatureJSON{
+		Protected: base64.RawURLEncoding.EncodeToString(protected),
+		Signature: &signature,
+	}
+	if s.Headers != nil {
+		hdrBuf, err := json.Marshal(s.Headers)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to marshal unprotected Headers`)
+		}
+		rawSig.Header = hdrBuf
+	}
+	return rawSig, nil
+}
+
+// protectedBytes returns the protected Headers as they are to be signed
+func (s *Signature) protectedBytes() ([]byte, error) {
+	if s.rawProtected != nil {
+		return s.rawProtected, nil
+	}
+	if s.Protected == nil {
+		return nil, nil
+	}
+	hdrBuf, err := json.Marshal(s.Protected)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal protected Headers`)
+	}
+	return hdrBuf, nil
+}
+
+// signingInput computes the JWS Signing Input for this signature
+// over the given payload
+func (s *Signature) signingInput(payload []byte) ([]byte, error) {
+	protected, err := s.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	b64, err := isBase64Payload(s.Protected)
+	if err != nil {
+		return nil, err
+	}
+	return signingInput(base64.RawURLEncoding.EncodeToString(protected), payload, b64), nil
+}
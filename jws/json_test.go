@@ -0,0 +1,429 @@
+package jws_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jwk"
+	"github.com/repenno/jwx-opa/jws"
+)
+
+// exampleGeneralSerialization is taken from https://tools.ietf.org/html/rfc7515#appendix-A.6.4
+const exampleGeneralSerialization = `{
+  "payload": "eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ",
+  "signatures": [
+    {
+      "protected": "eyJhbGciOiJSUzI1NiJ9",
+      "header": {"kid": "2010-12-29"},
+      "signature": "cC4hiUPoj9Eetdgtv3hF80EGrhuB__dzERat0XF9g2VtQgr9PJbu3XOiZj5RZmh7AAuHIm4Bh-0Qc_lF5YKt_O8W2Fp5jujGbds9uJdbF9CUAr7t1dnZcAcQjbKBYNX4BAynRFdiuB--f_nZLgrnbyTyWzO75vRK5h6xBArLIARNPvkSjtQBMHlb1L07Qe7K0GarZRmB_eSN9383LcOLn6_dO--xi12jzDwusC-eOkHWEsqtFZESc6BfI7noOPqvhJ1phCnvWh6IeYI2w9QOYEUipUTI8np6LbgGY9Fs98rqVt5AXLIhWkWywlVmtVrBp0igcN_IoypGlUPQGe77Rw"
+    },
+    {
+      "protected": "eyJhbGciOiJFUzI1NiJ9",
+      "header": {"kid": "e9bc097a-ce51-4036-9562-d2ade882db0d"},
+      "signature": "DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q"
+    }
+  ]
+}`
+
+// exampleFlattenedSerialization is taken from https://tools.ietf.org/html/rfc7515#appendix-A.7
+const exampleFlattenedSerialization = `{
+  "payload": "eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ",
+  "protected": "eyJhbGciOiJFUzI1NiJ9",
+  "header": {"kid": "e9bc097a-ce51-4036-9562-d2ade882db0d"},
+  "signature": "DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q"
+}`
+
+const exampleRSAPublicKey = `{
+  "kty":"RSA",
+  "n":"ofgWCuLjybRlzo0tZWJjNiuSfb4p4fAkd_wWJcyQoTbji9k0l8W26mPddxHmfHQp-Vaw-4qPCJrcS2mJPMEzP1Pt0Bm4d4QlL-yRT-SFd2lZS-pCgNMsD1W_YpRPEwOWvG6b32690r2jZ47soMZo9wGzjb_7OMg0LOL-bSf63kpaSHSXndS5z5rexMdbBYUsLA9e-KXBdQOS-UTo7WTBEMa2R2CapHg665xsmtdVMTBQY4uDZlxvb3qCo5ZwKh9kG4LT6_I5IhlJH7aGhyxXFvUK-DWNmoudF8NAco9_h9iaGNj8q2ethFkMLs91kzk2PAcDTW9gb54h4FRWyuXpoQ",
+  "e":"AQAB"
+}`
+
+const exampleECPublicKey = `{
+  "kty":"EC",
+  "crv":"P-256",
+  "x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU",
+  "y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"
+}`
+
+func materializeExampleKey(t *testing.T, src string) interface{} {
+	t.Helper()
+
+	keySet, err := jwk.ParseString(src)
+	if err != nil {
+		t.Fatalf("Failed to parse JWK: %s", err.Error())
+	}
+	key, err := keySet.Keys[0].Materialize()
+	if err != nil {
+		t.Fatalf("Failed to materialize key: %s", err.Error())
+	}
+	return key
+}
+
+func TestParseJSON(t *testing.T) {
+
+	t.Run("General Serialization", func(t *testing.T) {
+		msg, err := jws.ParseJSON([]byte(exampleGeneralSerialization))
+		if err != nil {
+			t.Fatalf("Failed to parse JSON serialization: %s", err.Error())
+		}
+		if string(msg.GetPayload()) != examplePayload {
+			t.Fatal("Mismatched payload")
+		}
+		signatures := msg.GetSignatures()
+		if len(signatures) != 2 {
+			t.Fatalf("Invalid number of signatures: %d", len(signatures))
+		}
+		for i, alg := range []jwa.SignatureAlgorithm{jwa.RS256, jwa.ES256} {
+			if signatures[i].ProtectedHeaders().GetAlgorithm() != alg {
+				t.Fatalf("Algorithm in header #%d does not match", i)
+			}
+			if string(signatures[i].RawProtectedHeaders()) != `{"alg":"`+alg.String()+`"}` {
+				t.Fatalf("Raw protected header #%d does not match", i)
+			}
+		}
+		kid, ok := signatures[1].PublicHeaders().Get(jws.KeyIDKey)
+		if !ok || kid != "e9bc097a-ce51-4036-9562-d2ade882db0d" {
+			t.Fatal("Unprotected kid does not match")
+		}
+	})
+	t.Run("Flattened Serialization", func(t *testing.T) {
+		msg, err := jws.ParseJSON([]byte(exampleFlattenedSerialization))
+		if err != nil {
+			t.Fatalf("Failed to parse JSON serialization: %s", err.Error())
+		}
+		if string(msg.GetPayload()) != examplePayload {
+			t.Fatal("Mismatched payload")
+		}
+		signatures := msg.GetSignatures()
+		if len(signatures) != 1 {
+			t.Fatalf("Invalid number of signatures: %d", len(signatures))
+		}
+		if signatures[0].ProtectedHeaders().GetAlgorithm() != jwa.ES256 {
+			t.Fatal("Algorithm in header does not match")
+		}
+	})
+	t.Run("Detect serialization", func(t *testing.T) {
+		for _, src := range []string{exampleCompactSerialization, exampleFlattenedSerialization, exampleGeneralSerialization} {
+			msg, err := jws.ParseString(src)
+			if err != nil {
+				t.Fatalf("Failed to parse JWS: %s", err.Error())
+			}
+			if string(msg.GetPayload()) != examplePayload {
+				t.Fatal("Mismatched payload")
+			}
+		}
+	})
+	t.Run("Mixed serialization", func(t *testing.T) {
+		_, err := jws.ParseJSON([]byte(`{"payload":"cGF5bG9hZA","signature":"c2ln","signatures":[{"protected":"eyJhbGciOiJIUzI1NiJ9","signature":"c2ln"}]}`))
+		if err == nil {
+			t.Fatal("Parsing JSON with both general and flattened members should fail")
+		}
+	})
+	t.Run("Missing signatures", func(t *testing.T) {
+		_, err := jws.ParseJSON([]byte(`{"payload":"cGF5bG9hZA","signatures":[]}`))
+		if err == nil {
+			t.Fatal("Parsing JSON without signatures should fail")
+		}
+	})
+	t.Run("Missing headers", func(t *testing.T) {
+		_, err := jws.ParseJSON([]byte(`{"payload":"cGF5bG9hZA","signatures":[{"signature":"c2ln"}]}`))
+		if err == nil {
+			t.Fatal("Parsing JSON without headers should fail")
+		}
+	})
+	t.Run("Duplicate header", func(t *testing.T) {
+		_, err := jws.ParseJSON([]byte(`{"payload":"cGF5bG9hZA","signatures":[{"protected":"eyJhbGciOiJIUzI1NiJ9","header":{"alg":"HS256"},"signature":"c2ln"}]}`))
+		if err == nil {
+			t.Fatal("Parsing JSON with a header both protected and unprotected should fail")
+		}
+	})
+	t.Run("Bad protected header", func(t *testing.T) {
+		_, err := jws.ParseJSON([]byte(`{"payload":"cGF5bG9hZA","signatures":[{"protected":"%bad%","signature":"c2ln"}]}`))
+		if err == nil {
+			t.Fatal("Parsing JSON with bad protected header should fail")
+		}
+	})
+}
+
+func TestVerifyJSON(t *testing.T) {
+
+	rsaKey := materializeExampleKey(t, exampleRSAPublicKey)
+	ecKey := materializeExampleKey(t, exampleECPublicKey)
+
+	t.Run("Each signature", func(t *testing.T) {
+		payload, err := jws.VerifyJSON([]byte(exampleGeneralSerialization), jwa.RS256, rsaKey)
+		if err != nil {
+			t.Fatalf("Failed to verify RS256 signature: %s", err.Error())
+		}
+		if string(payload) != examplePayload {
+			t.Fatal("Mismatched payload")
+		}
+		payload, err = jws.VerifyJSON([]byte(exampleGeneralSerialization), jwa.ES256, ecKey)
+		if err != nil {
+			t.Fatalf("Failed to verify ES256 signature: %s", err.Error())
+		}
+		if string(payload) != examplePayload {
+			t.Fatal("Mismatched payload")
+		}
+	})
+	t.Run("Wrong key", func(t *testing.T) {
+		_, err := jws.VerifyJSON([]byte(exampleGeneralSerialization), jwa.ES256, rsaKey)
+		if err == nil {
+			t.Fatal("Verification with the wrong key should fail")
+		}
+	})
+	t.Run("Flattened", func(t *testing.T) {
+		payload, err := jws.Verify([]byte(exampleFlattenedSerialization), jwa.ES256, ecKey)
+		if err != nil {
+			t.Fatalf("Failed to verify ES256 signature: %s", err.Error())
+		}
+		if string(payload) != examplePayload {
+			t.Fatal("Mismatched payload")
+		}
+	})
+	t.Run("Serialize RoundTrip", func(t *testing.T) {
+		msg, err := jws.ParseJSON([]byte(exampleGeneralSerialization))
+		if err != nil {
+			t.Fatalf("Failed to parse JSON serialization: %s", err.Error())
+		}
+		buf, err := jws.SerializeJSON(msg)
+		if err != nil {
+			t.Fatalf("Failed to serialize message: %s", err.Error())
+		}
+		msg2, err := jws.ParseJSON(buf)
+		if err != nil {
+			t.Fatalf("Failed to parse serialized message: %s", err.Error())
+		}
+		if bytes.Compare(msg.GetPayload(), msg2.GetPayload()) != 0 {
+			t.Fatal("Mismatched payload")
+		}
+		if _, err := jws.VerifyJSON(buf, jwa.RS256, rsaKey); err != nil {
+			t.Fatalf("Failed to verify serialized message: %s", err.Error())
+		}
+		if _, err := jws.VerifyJSON(buf, jwa.ES256, ecKey); err != nil {
+			t.Fatalf("Failed to verify serialized message: %s", err.Error())
+		}
+	})
+}
+
+func TestSignFlattened(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+	sharedKey := []byte("Avracadabra")
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var unprotected jws.StandardHeaders
+		if err := unprotected.Set(jws.KeyIDKey, "my-key"); err != nil {
+			t.Fatalf("Failed to set kid: %s", err.Error())
+		}
+		signed, err := jws.SignFlattened(payload, jwa.HS256, sharedKey, nil, &unprotected)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		verified, err := jws.Verify(signed, jwa.HS256, sharedKey)
+		if err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+		if bytes.Compare(payload, verified) != 0 {
+			t.Fatalf("Mismatched payload (%s):(%s)", payload, verified)
+		}
+
+		msg, err := jws.ParseByte(signed)
+		if err != nil {
+			t.Fatalf("Failed to parse message: %s", err.Error())
+		}
+		if kid, ok := msg.GetSignatures()[0].PublicHeaders().Get(jws.KeyIDKey); !ok || kid != "my-key" {
+			t.Fatal("Unprotected kid does not match")
+		}
+		buf, err := jws.SerializeFlattened(msg)
+		if err != nil {
+			t.Fatalf("Failed to serialize message: %s", err.Error())
+		}
+		if bytes.Compare(signed, buf) != 0 {
+			t.Fatal("Mismatched serializations")
+		}
+	})
+	t.Run("Mismatched algorithm", func(t *testing.T) {
+		var protected jws.StandardHeaders
+		if err := protected.Set(jws.AlgorithmKey, jwa.HS512); err != nil {
+			t.Fatalf("Failed to set alg: %s", err.Error())
+		}
+		_, err := jws.SignFlattened(payload, jwa.HS256, sharedKey, &protected, nil)
+		if err == nil {
+			t.Fatal("Signing with an algorithm different from the header should fail")
+		}
+	})
+	t.Run("Overlapping headers", func(t *testing.T) {
+		var protected, unprotected jws.StandardHeaders
+		if err := protected.Set(jws.KeyIDKey, "my-key"); err != nil {
+			t.Fatalf("Failed to set kid: %s", err.Error())
+		}
+		if err := unprotected.Set(jws.KeyIDKey, "my-key"); err != nil {
+			t.Fatalf("Failed to set kid: %s", err.Error())
+		}
+		_, err := jws.SignFlattened(payload, jwa.HS256, sharedKey, &protected, &unprotected)
+		if err == nil {
+			t.Fatal("Signing with overlapping headers should fail")
+		}
+	})
+}
+
+func TestSignMulti(t *testing.T) {
+	payload := []byte("Hello, World!")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var unprotected jws.StandardHeaders
+		if err := unprotected.Set(jws.KeyIDKey, "ec-key"); err != nil {
+			t.Fatalf("Failed to set kid: %s", err.Error())
+		}
+		msg, err := jws.SignMulti(payload,
+			jws.SignerConfig{Algorithm: jwa.RS256, Key: rsaKey},
+			jws.SignerConfig{Algorithm: jwa.ES256, Key: ecKey, Unprotected: &unprotected},
+		)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if len(msg.GetSignatures()) != 2 {
+			t.Fatalf("Invalid number of signatures: %d", len(msg.GetSignatures()))
+		}
+		buf, err := jws.SerializeJSON(msg)
+		if err != nil {
+			t.Fatalf("Failed to serialize message: %s", err.Error())
+		}
+
+		verified, err := jws.VerifyJSON(buf, jwa.RS256, &rsaKey.PublicKey)
+		if err != nil {
+			t.Fatalf("Failed to verify RS256 signature: %s", err.Error())
+		}
+		if bytes.Compare(payload, verified) != 0 {
+			t.Fatalf("Mismatched payload (%s):(%s)", payload, verified)
+		}
+		verified, err = jws.VerifyJSON(buf, jwa.ES256, &ecKey.PublicKey)
+		if err != nil {
+			t.Fatalf("Failed to verify ES256 signature: %s", err.Error())
+		}
+		if bytes.Compare(payload, verified) != 0 {
+			t.Fatalf("Mismatched payload (%s):(%s)", payload, verified)
+		}
+	})
+	t.Run("No signers", func(t *testing.T) {
+		_, err := jws.SignMulti(payload)
+		if err == nil {
+			t.Fatal("Signing without signers should fail")
+		}
+	})
+	t.Run("Bad key", func(t *testing.T) {
+		_, err := jws.SignMulti(payload,
+			jws.SignerConfig{Algorithm: jwa.RS256, Key: rsaKey},
+			jws.SignerConfig{Algorithm: jwa.ES256, Key: rsaKey},
+		)
+		if err == nil {
+			t.Fatal("Signing with a mismatched key should fail")
+		}
+	})
+}
+
+func TestMessageJSON(t *testing.T) {
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var msg jws.Message
+		if err := json.Unmarshal([]byte(exampleGeneralSerialization), &msg); err != nil {
+			t.Fatalf("Failed to unmarshal message: %s", err.Error())
+		}
+		if len(msg.Signatures) != 2 {
+			t.Fatalf("Expected 2 signatures, got %d", len(msg.Signatures))
+		}
+		buf, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("Failed to marshal message: %s", err.Error())
+		}
+		var members map[string]interface{}
+		if err := json.Unmarshal(buf, &members); err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %s", err.Error())
+		}
+		if payload := members["payload"]; payload != "eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" {
+			t.Fatalf("Payload should be base64url encoded, got %v", payload)
+		}
+		first := members["signatures"].([]interface{})[0].(map[string]interface{})
+		if first["protected"] != "eyJhbGciOiJSUzI1NiJ9" {
+			t.Fatalf("Protected Headers should be emitted as signed, got %v", first["protected"])
+		}
+		if _, ok := first["header"]; !ok {
+			t.Fatal("Missing unprotected Headers")
+		}
+
+		var again jws.Message
+		if err := json.Unmarshal(buf, &again); err != nil {
+			t.Fatalf("Failed to unmarshal message: %s", err.Error())
+		}
+		if !bytes.Equal(again.Payload, msg.Payload) {
+			t.Fatal("Mismatched payload")
+		}
+		for i, sig := range again.Signatures {
+			if !bytes.Equal(sig.Signature, msg.Signatures[i].Signature) {
+				t.Fatalf("Mismatched signature #%d", i)
+			}
+		}
+	})
+	t.Run("Signature", func(t *testing.T) {
+		var msg jws.Message
+		if err := json.Unmarshal([]byte(exampleGeneralSerialization), &msg); err != nil {
+			t.Fatalf("Failed to unmarshal message: %s", err.Error())
+		}
+		buf, err := json.Marshal(msg.Signatures[1])
+		if err != nil {
+			t.Fatalf("Failed to marshal signature: %s", err.Error())
+		}
+		var sig jws.Signature
+		if err := json.Unmarshal(buf, &sig); err != nil {
+			t.Fatalf("Failed to unmarshal signature: %s", err.Error())
+		}
+		if !bytes.Equal(sig.RawProtectedHeaders(), msg.Signatures[1].RawProtectedHeaders()) {
+			t.Fatal("Mismatched protected Headers")
+		}
+		if kid, _ := sig.Headers.Get(jws.KeyIDKey); kid != "e9bc097a-ce51-4036-9562-d2ade882db0d" {
+			t.Fatalf("Unexpected kid %v", kid)
+		}
+	})
+	t.Run("Compact", func(t *testing.T) {
+		msg, err := jws.ParseString(exampleCompactSerialization)
+		if err != nil {
+			t.Fatalf("Failed to parse message: %s", err.Error())
+		}
+		compact, err := msg.Compact()
+		if err != nil {
+			t.Fatalf("Failed to serialize message: %s", err.Error())
+		}
+		if string(compact) != exampleCompactSerialization {
+			t.Fatalf("Mismatched compact serialization: %s", compact)
+		}
+	})
+	t.Run("Compact errors", func(t *testing.T) {
+		msg, err := jws.ParseJSON([]byte(exampleGeneralSerialization))
+		if err != nil {
+			t.Fatalf("Failed to parse message: %s", err.Error())
+		}
+		if _, err := msg.Compact(); err == nil {
+			t.Fatal("Compact serialization of several signatures should fail")
+		}
+		msg.Signatures = msg.Signatures[:1]
+		if _, err := msg.Compact(); err == nil {
+			t.Fatal("Compact serialization of unprotected Headers should fail")
+		}
+	})
+}
@@ -21,6 +21,10 @@ package jws
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"strings"
@@ -30,45 +34,309 @@ import (
 	"github.com/repenno/jwx-opa/jwk"
 	"github.com/repenno/jwx-opa/jws/sign"
 	"github.com/repenno/jwx-opa/jws/verify"
+	"github.com/repenno/jwx-opa/mldsa"
 )
 
 // SignLiteral generates a Signature for the given Payload and Headers, and serializes
 // it in compact serialization format. In this format you may NOT use
-// multiple signers.
+// multiple signers. To sign with multiple keys, use `SignMulti`.
 //
 func SignLiteral(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, hdrBuf []byte) ([]byte, error) {
-	encodedHdr := base64.RawURLEncoding.EncodeToString(hdrBuf)
-	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
-	signingInput := strings.Join(
+	return signLiteral(payload, alg, key, hdrBuf, sign.New)
+}
+
+// signLiteral implements SignLiteral using the signer created by `newSigner`
+func signLiteral(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, hdrBuf []byte, newSigner sign.SignerFactory) ([]byte, error) {
+	encodedHdr, b64, err := encodeCompactHeaders(hdrBuf)
+	if err != nil {
+		return nil, err
+	}
+	// https://tools.ietf.org/html/rfc7797#section-5.2
+	if !b64 && bytes.IndexByte(payload, '.') >= 0 {
+		return nil, errors.New(`unencoded Payload must not contain '.' in compact serialization`)
+	}
+	encodedPayload := string(encodePayload(payload, b64))
+	encodedSignature, err := signCompact(encodedHdr, encodedPayload, alg, key, newSigner)
+	if err != nil {
+		return nil, err
+	}
+	compactSerialization := strings.Join(
 		[]string{
 			encodedHdr,
 			encodedPayload,
+			encodedSignature,
 		}, ".",
 	)
-	signer, err := sign.New(alg)
+	return []byte(compactSerialization), nil
+}
+
+// SignDetached generates a Signature for the given Payload and Headers, and
+// serializes it in compact serialization format with a detached Payload, as
+// described in https://tools.ietf.org/html/rfc7515#appendix-F. The Payload
+// is left out of the result, and must be transmitted separately. If `hdrBuf`
+// is nil, Headers containing only the algorithm are used. The WithHeaders
+// option does not apply, as the Headers are given by `hdrBuf`.
+func SignDetached(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, hdrBuf []byte, options ...SignOption) ([]byte, error) {
+	if hdrBuf == nil {
+		var err error
+		if hdrBuf, err = algorithmHeaders(alg); err != nil {
+			return nil, err
+		}
+	}
+	encodedHdr, b64, err := encodeCompactHeaders(hdrBuf)
 	if err != nil {
-		return nil, errors.Wrap(err, `failed to create signer`)
+		return nil, err
 	}
-	signature, err := signer.Sign([]byte(signingInput), key)
+	cfg := newSignConfig(options)
+	if key, err = cfg.signingKey(alg, key); err != nil {
+		return nil, err
+	}
+	encodedSignature, err := signCompact(encodedHdr, string(encodePayload(payload, b64)), alg, key, cfg.newSigner)
 	if err != nil {
-		return nil, errors.Wrap(err, `failed to sign Payload`)
+		return nil, err
 	}
-	encodedSignature := base64.RawURLEncoding.EncodeToString(signature)
 	compactSerialization := strings.Join(
 		[]string{
-			signingInput,
+			encodedHdr,
+			"",
 			encodedSignature,
 		}, ".",
 	)
 	return []byte(compactSerialization), nil
 }
 
+// encodeCompactHeaders encodes the Headers for the compact serialization, and
+// reports whether the Payload is to be base64url encoded according to them
+func encodeCompactHeaders(hdrBuf []byte) (string, bool, error) {
+	var hdr StandardHeaders
+	if err := json.Unmarshal(hdrBuf, &hdr); err != nil {
+		return "", false, errors.Wrap(err, `failed to parse JOSE Headers`)
+	}
+	b64, err := isBase64Payload(&hdr)
+	if err != nil {
+		return "", false, errors.Wrap(err, `invalid Payload encoding`)
+	}
+	return base64.RawURLEncoding.EncodeToString(hdrBuf), b64, nil
+}
+
+// signCompact signs the encoded Headers and Payload with the signer created
+// by `newSigner`, and returns the encoded Signature
+func signCompact(encodedHdr, encodedPayload string, alg jwa.SignatureAlgorithm, key interface{}, newSigner sign.SignerFactory) (string, error) {
+	signingInput := strings.Join(
+		[]string{
+			encodedHdr,
+			encodedPayload,
+		}, ".",
+	)
+	signer, err := newSigner(alg)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to create signer`)
+	}
+	signature, err := signer.Sign([]byte(signingInput), key)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to sign Payload`)
+	}
+	return base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
 // SignWithOption generates a Signature for the given Payload, and serializes
 // it in compact serialization format. In this format you may NOT use
-// multiple signers.
+// multiple signers. To sign with multiple keys, use `SignMulti`.
 //
 // If you would like to pass custom Headers, use the WithHeaders option.
-func SignWithOption(payload []byte, alg jwa.SignatureAlgorithm, key interface{}) ([]byte, error) {
+func SignWithOption(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, options ...SignOption) ([]byte, error) {
+	cfg := newSignConfig(options)
+	return cfg.sign(payload, alg, key, cfg.headers)
+}
+
+// SignWithHeaders generates a Signature for the given Payload with the
+// given protected Headers, and serializes it in compact serialization
+// format. The "alg" header parameter is set when missing, and must
+// otherwise match `alg`. The Headers are serialized deterministically,
+// with the private header parameters sorted by name.
+func SignWithHeaders(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, hdr Headers, options ...SignOption) ([]byte, error) {
+	return newSignConfig(options).sign(payload, alg, key, hdr)
+}
+
+func (cfg *signConfig) sign(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, hdr Headers) ([]byte, error) {
+
+	key, err := cfg.signingKey(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	hdrBuf, err := signingHeaders(alg, hdr)
+	if err != nil {
+		return nil, err
+	}
+	return signLiteral(payload, alg, key, hdrBuf, cfg.newSigner)
+}
+
+// signingKey returns the key to sign with using `alg`. The "none" algorithm
+// is rejected unless allowed by the WithAllowNone option, in which case the
+// key is replaced with sign.UnsafeAllowNone.
+func (cfg *signConfig) signingKey(alg jwa.SignatureAlgorithm, key interface{}) (interface{}, error) {
+	if alg != jwa.NoSignature {
+		return key, nil
+	}
+	if !cfg.allowNone {
+		return nil, errors.Errorf(`algorithm %s is not allowed`, alg)
+	}
+	return sign.UnsafeAllowNone, nil
+}
+
+// newSigner creates the signer of `alg`, which is deterministic for ECDSA
+// algorithms when requested by the WithDeterministicECDSA option
+func (cfg *signConfig) newSigner(alg jwa.SignatureAlgorithm) (sign.Signer, error) {
+	if cfg.deterministicECDSA && alg.KeyType() == jwa.EC {
+		return sign.NewDeterministicECDSA(alg)
+	}
+	return sign.New(alg)
+}
+
+// signingHeaders returns the marshaled copy of `hdr` whose "alg" header
+// parameter is `alg`. Going through StandardHeaders leaves the caller's
+// Headers untouched, and orders the header parameters deterministically.
+func signingHeaders(alg jwa.SignatureAlgorithm, hdr Headers) ([]byte, error) {
+	if hdr == nil {
+		return algorithmHeaders(alg)
+	}
+
+	headers, err := copyHeaders(hdr)
+	if err != nil {
+		return nil, err
+	}
+	switch headers.Algorithm {
+	case jwa.NoValue:
+		headers.Algorithm = alg
+	case alg:
+	default:
+		return nil, errors.Errorf(`%s header parameter %s does not match algorithm %s`, AlgorithmKey, headers.Algorithm, alg)
+	}
+
+	hdrBuf, err := json.Marshal(headers)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal Headers`)
+	}
+	return hdrBuf, nil
+}
+
+// copyHeaders returns a copy of `hdr` as StandardHeaders
+func copyHeaders(hdr Headers) (*StandardHeaders, error) {
+	headers := &StandardHeaders{}
+	if hdr == nil {
+		return headers, nil
+	}
+
+	buf, err := json.Marshal(hdr)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal Headers`)
+	}
+	if err := json.Unmarshal(buf, headers); err != nil {
+		return nil, errors.Wrap(err, `failed to copy Headers`)
+	}
+	return headers, nil
+}
+
+// SignWithJWK generates a Signature for the given Payload using the JWK
+// `key`, and serializes it in compact serialization format. The algorithm
+// declared by the key is used, or else a default one for its type, curve
+// or size. The key ID, if any, is copied to the "kid" header parameter.
+// Keys whose "use" or "key_ops" parameters do not allow signing are
+// rejected.
+func SignWithJWK(payload []byte, key jwk.Key, options ...SignOption) ([]byte, error) {
+
+	if !isSigningKey(key) {
+		return nil, errors.New(`key cannot be used for signing`)
+	}
+	keyVal, err := key.Materialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to materialize key")
+	}
+	alg := key.GetAlgorithm()
+	if alg == jwa.NoValue {
+		if alg, err = defaultAlgorithm(keyVal); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := newSignConfig(options)
+	hdr, err := copyHeaders(cfg.headers)
+	if err != nil {
+		return nil, err
+	}
+	if kid := key.GetKeyID(); kid != "" {
+		switch hdr.KeyID {
+		case "":
+			hdr.KeyID = kid
+		case kid:
+		default:
+			return nil, errors.Errorf(`%s header parameter %s does not match key ID %s`, KeyIDKey, hdr.KeyID, kid)
+		}
+	}
+	return cfg.sign(payload, alg, keyVal, hdr)
+}
+
+// isSigningKey checks that the "use" and "key_ops" parameters of `key`
+// allow computing a signature
+func isSigningKey(key jwk.Key) bool {
+
+	if use := key.GetKeyUsage(); use != "" && use != string(jwk.ForSignature) {
+		return false
+	}
+	if ops := key.GetKeyOps(); len(ops) > 0 {
+		for _, op := range ops {
+			if op == jwk.KeyOpSign {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// defaultAlgorithm picks the algorithm to sign with a private `key` that
+// does not declare one
+func defaultAlgorithm(key interface{}) (jwa.SignatureAlgorithm, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return jwa.RS256, nil
+	case ed25519.PrivateKey:
+		return jwa.EdDSA, nil
+	case *mldsa.PrivateKey:
+		return jwa.SignatureAlgorithm(key.Parameters().Name()), nil
+	case *ecdsa.PrivateKey:
+		if alg, ok := ecdsaAlgorithms[key.Params().Name]; ok {
+			return alg, nil
+		}
+		return jwa.NoValue, errors.Errorf(`no default algorithm for curve %s`, key.Params().Name)
+	case []byte:
+		// The HMAC key should be at least as long as the hash output
+		switch {
+		case len(key) >= 64:
+			return jwa.HS512, nil
+		case len(key) >= 48:
+			return jwa.HS384, nil
+		default:
+			return jwa.HS256, nil
+		}
+	default:
+		return jwa.NoValue, errors.Errorf(`no default algorithm for key of type %T`, key)
+	}
+}
+
+// ecdsaAlgorithms maps each curve to the algorithm used by default with it
+var ecdsaAlgorithms = map[string]jwa.SignatureAlgorithm{
+	"P-256":           jwa.ES256,
+	"P-384":           jwa.ES384,
+	"P-521":           jwa.ES512,
+	"secp256k1":       jwa.ES256K,
+	"brainpoolP256r1": jwa.ESB256,
+	"brainpoolP384r1": jwa.ESB384,
+	"brainpoolP512r1": jwa.ESB512,
+}
+
+// algorithmHeaders returns the marshaled Headers containing only `alg`
+func algorithmHeaders(alg jwa.SignatureAlgorithm) ([]byte, error) {
 	var headers Headers = &StandardHeaders{}
 
 	err := headers.Set(AlgorithmKey, alg)
@@ -80,130 +348,461 @@ func SignWithOption(payload []byte, alg jwa.SignatureAlgorithm, key interface{})
 	if err != nil {
 		return nil, errors.Wrap(err, `failed to marshal Headers`)
 	}
-	return SignLiteral(payload, alg, key, hdrBuf)
+	return hdrBuf, nil
+}
+
+// SignFlattened generates a Signature for the given Payload, and serializes
+// it in the flattened JSON serialization format. Either of `protected` and
+// `unprotected` Headers may be nil. If the protected Headers do not specify
+// an algorithm, `alg` is set in them. The WithHeaders option does not
+// apply, as the Headers are given explicitly.
+func SignFlattened(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, protected, unprotected Headers, options ...SignOption) ([]byte, error) {
+
+	cfg := newSignConfig(options)
+	key, err := cfg.signingKey(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := newSignature(payload, alg, key, protected, unprotected, cfg.newSigner)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create signature`)
+	}
+	return SerializeFlattened(&Message{
+		Payload:    payload,
+		Signatures: []*Signature{sig},
+	})
+}
+
+// SignerConfig describes one of the signatures to be generated by `SignMulti`:
+// the algorithm and key to sign with, and the Headers of the signature.
+// Either of the Headers may be nil. DeterministicECDSA has the effect of
+// the WithDeterministicECDSA option on the signature.
+type SignerConfig struct {
+	Algorithm          jwa.SignatureAlgorithm
+	Key                interface{}
+	Protected          Headers
+	Unprotected        Headers
+	DeterministicECDSA bool
+}
+
+// SignMulti generates one Signature per entry in `signers` for the given
+// Payload, and returns the resulting Message. Use `SerializeJSON` to
+// serialize it in the general JSON serialization format.
+func SignMulti(payload []byte, signers ...SignerConfig) (*Message, error) {
+
+	if len(signers) == 0 {
+		return nil, errors.New(`no signers provided`)
+	}
+
+	msg := &Message{Payload: payload}
+	for i, signer := range signers {
+		cfg := &signConfig{deterministicECDSA: signer.DeterministicECDSA}
+		sig, err := newSignature(payload, signer.Algorithm, signer.Key, signer.Protected, signer.Unprotected, cfg.newSigner)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to create signature #%d`, i)
+		}
+		msg.Signatures = append(msg.Signatures, sig)
+	}
+	return msg, nil
+}
+
+// newSignature signs the Payload using `alg` and `key` with the signer
+// created by `newSigner`, and returns the resulting Signature along with
+// its protected and unprotected Headers
+func newSignature(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, protected, unprotected Headers, newSigner sign.SignerFactory) (*Signature, error) {
+
+	if protected == nil {
+		protected = &StandardHeaders{}
+	}
+	switch hdrAlg := protected.GetAlgorithm(); hdrAlg {
+	case jwa.NoValue:
+		if err := protected.Set(AlgorithmKey, alg); err != nil {
+			return nil, errors.Wrap(err, "Failed to set alg value")
+		}
+	case alg:
+	default:
+		return nil, errors.Errorf(`algorithm %s does not match protected Headers algorithm %s`, alg, hdrAlg)
+	}
+
+	hdrBuf, err := json.Marshal(protected)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal protected Headers`)
+	}
+	if unprotected != nil {
+		unprotectedBuf, err := json.Marshal(unprotected)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to marshal unprotected Headers`)
+		}
+		if err := checkDisjointHeaders(hdrBuf, unprotectedBuf); err != nil {
+			return nil, err
+		}
+	}
+
+	sig := &Signature{
+		Headers:      unprotected,
+		Protected:    protected,
+		rawProtected: hdrBuf,
+	}
+	signingInput, err := sig.signingInput(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to compute signing input`)
+	}
+	signer, err := newSigner(alg)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create signer`)
+	}
+	if sig.Signature, err = signer.Sign(signingInput, key); err != nil {
+		return nil, errors.Wrap(err, `failed to sign Payload`)
+	}
+	return sig, nil
 }
 
 // Verify checks if the given JWS message is verifiable using `alg` and `key`.
 // If the verification is successful, `err` is nil, and the content of the
-// Payload that was signed is returned. If you need more fine-grained
-// control of the verification process, manually call `Parse`, generate a
-// verifier, and call `Verify` on the parsed JWS message object.
-func Verify(buf []byte, alg jwa.SignatureAlgorithm, key interface{}) (ret []byte, err error) {
-
-	verifier, err := verify.New(alg)
+// Payload that was signed is returned. Messages in either of the JSON
+// serialization formats are accepted as well, in which case the verification
+// succeeds as soon as one of the signatures matches.
+// If you need more fine-grained control of the verification process,
+// manually call `Parse`, generate a verifier, and call `Verify` on the
+// parsed JWS message object.
+//
+// Critical extension header parameters that are not understood by this
+// package are rejected, unless a handler is provided for them using the
+// WithCriticalHandler option. Use the WithAllowedAlgorithms option to bind
+// the verification to the "alg" protected header parameter.
+func Verify(buf []byte, alg jwa.SignatureAlgorithm, key interface{}, options ...VerifyOption) (ret []byte, err error) {
+	m, err := VerifyMessage(buf, alg, key, options...)
 	if err != nil {
+		return nil, err
+	}
+	return m.Payload, nil
+}
+
+// VerifyMessage verifies the JWS message like `Verify`, and returns the
+// verified Message. The Message only holds the signature that was
+// verified along with its protected Headers, which can be trusted. The
+// unprotected Headers are not covered by the signature, and are left out.
+// The algorithm and key that verified it are available from the Message
+// as well.
+func VerifyMessage(buf []byte, alg jwa.SignatureAlgorithm, key interface{}, options ...VerifyOption) (*Message, error) {
+
+	if _, err := verify.New(alg); err != nil {
 		return nil, errors.Wrap(err, "failed to create verifier")
 	}
 
+	cfg := newVerifyConfig(options)
+	m, err := cfg.parseMessage(buf)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.verifyMessage(m, withKey(alg, key))
+}
+
+// VerifyDetached checks if the given JWS message, serialized in compact
+// serialization format with a detached Payload, is verifiable using `alg`
+// and `key` over the separately supplied `payload`. See
+// https://tools.ietf.org/html/rfc7515#appendix-F
+func VerifyDetached(buf []byte, payload []byte, alg jwa.SignatureAlgorithm, key interface{}, options ...VerifyOption) error {
+
+	if _, err := verify.New(alg); err != nil {
+		return errors.Wrap(err, "failed to create verifier")
+	}
+
 	buf = bytes.TrimSpace(buf)
 	if len(buf) == 0 {
-		return nil, errors.New(`attempt to verify empty buffer`)
+		return errors.New(`attempt to verify empty buffer`)
 	}
 
 	parts, err := SplitCompact(string(buf[:]))
 	if err != nil {
-		return nil, errors.Wrap(err, `failed extract from compact serialization format`)
+		return errors.Wrap(err, `failed extract from compact serialization format`)
+	}
+	if parts[1] != "" {
+		return errors.New(`Payload is not detached`)
+	}
+	cfg := newVerifyConfig(options)
+	m, err := parseCompact(string(buf[:]), newParseConfig(cfg.parseOptions))
+	if err != nil {
+		return errors.Wrap(err, `failed to parse compact serialization format`)
 	}
+	m.Payload = payload
 
-	signingInput := strings.Join(
-		[]string{
-			parts[0],
-			parts[1],
-		}, ".",
-	)
+	_, err = cfg.verifyMessage(m, withKey(alg, key))
+	return err
+}
 
-	decodedSignature, err := base64.RawURLEncoding.DecodeString(parts[2])
+// VerifyWithJWK verifies the JWS message using the specified JWK
+func VerifyWithJWK(buf []byte, key jwk.Key, options ...VerifyOption) (payload []byte, err error) {
+	m, err := VerifyMessageWithJWK(buf, key, options...)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to decode signature")
+		return nil, err
 	}
-	if err := verifier.Verify([]byte(signingInput), decodedSignature, key); err != nil {
-		return nil, errors.Wrap(err, "Failed to verify message")
+	return m.Payload, nil
+}
+
+// VerifyMessageWithJWK verifies the JWS message like `VerifyWithJWK`, and
+// returns the verified Message. The Message only holds the signature that
+// was verified along with its protected Headers, as described for
+// `VerifyMessage`.
+func VerifyMessageWithJWK(buf []byte, key jwk.Key, options ...VerifyOption) (*Message, error) {
+
+	if _, err := key.Materialize(); err != nil {
+		return nil, errors.Wrap(err, "Failed to materialize key")
 	}
 
-	if decodedPayload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil {
-		return decodedPayload, nil
+	cfg := newVerifyConfig(options)
+	m, err := cfg.parseMessage(buf)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.Wrap(err, "Failed to decode Payload")
+	return cfg.verifyMessage(m, cfg.withJWKs([]jwk.Key{key}))
 }
 
-// VerifyWithJWK verifies the JWS message using the specified JWK
-func VerifyWithJWK(buf []byte, key jwk.Key) (payload []byte, err error) {
+// VerifyWithJWKSet verifies the JWS message using JWK key set.
+// The keys are selected using the "kid" header parameter of the message.
+// Only keys whose "use" and "key_ops" parameters allow verification, and
+// whose "alg" parameter agrees with the one of the message, are picked up.
+// The selection can be further restricted by providing a key accept
+// function using the WithKeyAccept option. Messages without a matching
+// key ID are rejected, unless the WithKeyFallback option is given.
+//
+// Each key is used with the algorithm it declares. When the
+// WithAllowedAlgorithms option is given, keys that do not declare an
+// algorithm are used with the "alg" protected header parameter instead.
+func VerifyWithJWKSet(buf []byte, keyset *jwk.Set, options ...VerifyOption) (payload []byte, err error) {
+	m, err := VerifyMessageWithJWKSet(buf, keyset, options...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Payload, nil
+}
 
-	keyVal, err := key.Materialize()
+// VerifyMessageWithJWKSet verifies the JWS message like
+// `VerifyWithJWKSet`, and returns the verified Message. The Message only
+// holds the signature that was verified along with its protected Headers,
+// as described for `VerifyMessage`, and the key of the set that verified
+// it is available from the Message.
+func VerifyMessageWithJWKSet(buf []byte, keyset *jwk.Set, options ...VerifyOption) (*Message, error) {
+
+	cfg := newVerifyConfig(options)
+	m, err := cfg.parseMessage(buf)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to materialize key")
+		return nil, err
+	}
+	verified, err := cfg.verifyMessage(m, cfg.withJWKSet(keyset.Keys))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to verify with any of the keys")
 	}
-	return Verify(buf, key.GetAlgorithm(), keyVal)
+	return verified, nil
 }
 
-// VerifyWithJWKSet verifies the JWS message using JWK key set.
-// By default it will only pick up keys that have the "use" key
-// set to either "sig" or "enc", but you can override it by
-// providing a keyaccept function.
-func VerifyWithJWKSet(buf []byte, keyset *jwk.Set) (payload []byte, err error) {
-
-	for _, key := range keyset.Keys {
-		payload, err := VerifyWithJWK(buf, key)
-		if err == nil {
-			return payload, nil
-		}
+// VerifyWithEmbeddedJWK verifies the JWS message using the public key
+// embedded in the "jwk" protected header parameter of each signature.
+// The embedded key is only used when the caller trusts it, either by its
+// JWK Thumbprint using the WithTrustedThumbprints option, or through the
+// WithTrustedKeyFunc option. Otherwise the verification fails.
+func VerifyWithEmbeddedJWK(buf []byte, options ...VerifyOption) (payload []byte, err error) {
+	m, err := VerifyMessageWithEmbeddedJWK(buf, options...)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("failed to verify with any of the keys")
+	return m.Payload, nil
 }
 
-// ParseByte parses a JWS value serialized via compact serialization and provided as []byte.
-func ParseByte(jwsCompact []byte) (m *Message, err error) {
-	return parseCompact(string(jwsCompact[:]))
+// VerifyMessageWithEmbeddedJWK verifies the JWS message like
+// `VerifyWithEmbeddedJWK`, and returns the verified Message. The Message
+// only holds the signature that was verified along with its protected
+// Headers, which include the embedded key, as described for `VerifyMessage`.
+func VerifyMessageWithEmbeddedJWK(buf []byte, options ...VerifyOption) (*Message, error) {
+
+	cfg := newVerifyConfig(options)
+	m, err := cfg.parseMessage(buf)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := cfg.verifyMessage(m, cfg.withEmbeddedJWK())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to verify with the embedded key")
+	}
+	return verified, nil
+}
+
+// VerifyWithX509 verifies the JWS message using the public key of the
+// certificate found first in the "x5c" header parameter of each signature.
+// The certificate chain must be valid up to one of the `roots`, or up to
+// the system roots if `roots` is nil, and the certificate must match the
+// "x5t" and "x5t#S256" header parameters when they are present.
+// The "x5u" header parameter is not dereferenced.
+func VerifyWithX509(buf []byte, roots *x509.CertPool, options ...VerifyOption) (payload []byte, err error) {
+	m, err := VerifyMessageWithX509(buf, roots, options...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Payload, nil
+}
+
+// VerifyMessageWithX509 verifies the JWS message like `VerifyWithX509`,
+// and returns the verified Message. The Message only holds the signature
+// that was verified along with its protected Headers, as described for
+// `VerifyMessage`, and the public key of the certificate that verified it
+// is available from the Message.
+func VerifyMessageWithX509(buf []byte, roots *x509.CertPool, options ...VerifyOption) (*Message, error) {
+
+	cfg := newVerifyConfig(options)
+	m, err := cfg.parseMessage(buf)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := cfg.verifyMessage(m, cfg.withX509(roots))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to verify with the certificate chain")
+	}
+	return verified, nil
+}
+
+// VerifyJSON checks if the given JWS message, serialized using the JSON
+// serialization format, is verifiable using `alg` and `key`. Each of the
+// signatures in the message is tried in turn, and the verification
+// succeeds as soon as one of them matches. Upon success the Payload
+// that was signed is returned.
+func VerifyJSON(buf []byte, alg jwa.SignatureAlgorithm, key interface{}, options ...VerifyOption) ([]byte, error) {
+
+	if _, err := verify.New(alg); err != nil {
+		return nil, errors.Wrap(err, "failed to create verifier")
+	}
+
+	cfg := newVerifyConfig(options)
+	m, err := parseJSON(buf, newParseConfig(cfg.parseOptions))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse JSON serialization format`)
+	}
+	if _, err := cfg.verifyMessage(m, withKey(alg, key)); err != nil {
+		return nil, err
+	}
+	return m.Payload, nil
+}
+
+// parseMessage parses a JWS message in any of the serialization formats
+func (cfg *verifyConfig) parseMessage(buf []byte) (*Message, error) {
+
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 {
+		return nil, errors.New(`attempt to verify empty buffer`)
+	}
+	m, err := parse(buf, newParseConfig(cfg.parseOptions))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse JWS message`)
+	}
+	return m, nil
+}
+
+// ParseByte parses a JWS value provided as []byte. The serialization format
+// (compact, flattened JSON or general JSON) is detected automatically.
+func ParseByte(jwsCompact []byte, options ...ParseOption) (m *Message, err error) {
+	return parse(jwsCompact, newParseConfig(options))
+}
+
+// ParseString parses a JWS value provided as string. The serialization format
+// (compact, flattened JSON or general JSON) is detected automatically.
+func ParseString(s string, options ...ParseOption) (*Message, error) {
+	return parse([]byte(s), newParseConfig(options))
 }
 
-// ParseString parses a JWS value serialized via compact serialization and provided as string.
-func ParseString(s string) (*Message, error) {
-	return parseCompact(s)
+// parse detects the serialization format of a JWS value and parses it
+func parse(buf []byte, cfg *parseConfig) (*Message, error) {
+	buf = bytes.TrimSpace(buf)
+	if err := checkSize(`message`, len(buf), cfg.maxSize); err != nil {
+		return nil, err
+	}
+	if len(buf) > 0 && buf[0] == '{' {
+		return parseJSON(buf, cfg)
+	}
+	return parseCompact(string(buf[:]), cfg)
 }
 
+// ErrJWECompact is returned when a value in compact serialization format
+// has five segments, which denotes a JWE rather than a JWS
+var ErrJWECompact = errors.New(`compact serialization with five segments is a JWE, not a JWS`)
+
 // SplitCompact splits a JWT and returns its three parts
 // separately: Protected Headers, Payload and Signature.
+// Values with any other number of parts are rejected.
 func SplitCompact(jwsCompact string) ([]string, error) {
 
-	parts := strings.Split(jwsCompact, ".")
-	if len(parts) < 3 {
-		return nil, errors.New("Failed to split compact serialization")
+	switch n := strings.Count(jwsCompact, "."); n {
+	case 2:
+		return strings.Split(jwsCompact, "."), nil
+	case 4:
+		return nil, ErrJWECompact
+	default:
+		return nil, errors.Errorf("Failed to split compact serialization: expected 3 parts, found %d", n+1)
 	}
-	return parts, nil
 }
 
 // parseCompact parses a JWS value serialized via compact serialization.
-func parseCompact(str string) (m *Message, err error) {
+func parseCompact(str string, cfg *parseConfig) (m *Message, err error) {
 
-	var decodedHeader, decodedPayload, decodedSignature []byte
+	var decodedPayload []byte
+	if err := checkSize(`message`, len(str), cfg.maxSize); err != nil {
+		return nil, err
+	}
 	parts, err := SplitCompact(str)
 	if err != nil {
 		return nil, errors.Wrap(err, `invalid compact serialization format`)
 	}
 
-	if decodedHeader, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
-		return nil, errors.Wrap(err, `failed to decode Headers`)
+	if err := checkSize(`Headers`, base64.RawURLEncoding.DecodedLen(len(parts[0])), cfg.maxHeaderSize); err != nil {
+		return nil, err
 	}
-	var hdr StandardHeaders
-	if err := json.Unmarshal(decodedHeader, &hdr); err != nil {
-		return nil, errors.Wrap(err, `failed to parse JOSE Headers`)
+	hdr, decodedHeader, err := parseCompactHeaders(parts[0])
+	if err != nil {
+		return nil, err
 	}
-
-	if decodedPayload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
-		return nil, errors.Wrap(err, `failed to decode Payload`)
+	b64, err := isBase64Payload(hdr)
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid Payload encoding`)
 	}
 
-	if len(parts) > 2 {
-		if decodedSignature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
-			return nil, errors.Wrap(err, `failed to decode Signature`)
+	if !b64 {
+		if err := checkSize(`Payload`, len(parts[1]), cfg.maxPayloadSize); err != nil {
+			return nil, err
+		}
+		decodedPayload = []byte(parts[1])
+	} else {
+		if err := checkSize(`Payload`, base64.RawURLEncoding.DecodedLen(len(parts[1])), cfg.maxPayloadSize); err != nil {
+			return nil, err
 		}
+		if decodedPayload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+			return nil, errors.Wrap(err, `failed to decode Payload`)
+		}
+	}
+
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decode Signature`)
 	}
 
 	var msg Message
 	msg.Payload = decodedPayload
 	msg.Signatures = append(msg.Signatures, &Signature{
-		Protected: &hdr,
-		Signature: decodedSignature,
+		Protected:    hdr,
+		Signature:    decodedSignature,
+		rawProtected: decodedHeader,
 	})
 	return &msg, nil
 }
+
+// parseCompactHeaders decodes and parses the encoded Headers of a compact
+// serialization, returning both the parsed and the decoded Headers
+func parseCompactHeaders(encodedHdr string) (*StandardHeaders, []byte, error) {
+
+	decodedHeader, err := base64.RawURLEncoding.DecodeString(encodedHdr)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, `failed to decode Headers`)
+	}
+	var hdr StandardHeaders
+	if err := json.Unmarshal(decodedHeader, &hdr); err != nil {
+		return nil, nil, errors.Wrap(err, `failed to parse JOSE Headers`)
+	}
+	return &hdr, decodedHeader, nil
+}
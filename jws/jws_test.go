@@ -2,21 +2,29 @@ package jws_test
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
+	"io"
 	"math/big"
 	"strings"
 	"testing"
 
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/curves"
 	"github.com/repenno/jwx-opa/jwa"
 	"github.com/repenno/jwx-opa/jwk"
 	"github.com/repenno/jwx-opa/jws"
 	"github.com/repenno/jwx-opa/jws/sign"
 	"github.com/repenno/jwx-opa/jws/verify"
+	"github.com/repenno/jwx-opa/mldsa"
 )
 
 const examplePayload = `{"iss":"joe",` + "\r\n" + ` "exp":1300819380,` + "\r\n" + ` "http://example.com/is_root":true}`
@@ -43,6 +51,18 @@ func TestParseErrors(t *testing.T) {
 			t.Fatalf("Parsing compact serialization with less than 3 parts should be an error")
 		}
 	})
+	t.Run("Compact extra parts", func(t *testing.T) {
+		_, err := jws.ParseString(exampleCompactSerialization + ".extra")
+		if err == nil {
+			t.Fatal("Parsing compact serialization with more than 3 parts should be an error")
+		}
+	})
+	t.Run("Compact JWE", func(t *testing.T) {
+		_, err := jws.ParseString(exampleCompactSerialization + ".iv.tag")
+		if errors.Cause(err) != jws.ErrJWECompact {
+			t.Fatalf("Parsing compact serialization with 5 parts should report a JWE: %v", err)
+		}
+	})
 	t.Run("Compact bad header", func(t *testing.T) {
 		parts := strings.Split(exampleCompactSerialization, ".")
 		parts[0] = "%badvalue%"
@@ -75,6 +95,98 @@ func TestParseErrors(t *testing.T) {
 	})
 }
 
+func TestParseLimits(t *testing.T) {
+
+	parts := strings.Split(exampleCompactSerialization, ".")
+	headerSize := base64.RawURLEncoding.DecodedLen(len(parts[0]))
+	payloadSize := len(examplePayload)
+
+	tests := []struct {
+		name    string
+		options []jws.ParseOption
+		valid   bool
+	}{
+		{"No limits", nil, true},
+		{"Within limits", []jws.ParseOption{jws.WithMaxSize(len(exampleCompactSerialization)), jws.WithMaxHeaderSize(headerSize), jws.WithMaxPayloadSize(payloadSize)}, true},
+		{"Message too large", []jws.ParseOption{jws.WithMaxSize(len(exampleCompactSerialization) - 1)}, false},
+		{"Headers too large", []jws.ParseOption{jws.WithMaxHeaderSize(headerSize - 1)}, false},
+		{"Payload too large", []jws.ParseOption{jws.WithMaxPayloadSize(payloadSize - 1)}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := jws.ParseString(exampleCompactSerialization, test.options...)
+			if test.valid && err != nil {
+				t.Fatalf("Parsing failed: %s", err.Error())
+			}
+			if !test.valid && err == nil {
+				t.Fatal("Parsing an oversized message should fail")
+			}
+		})
+	}
+
+	t.Run("JSON serialization", func(t *testing.T) {
+		src := `{"payload":"cGF5bG9hZA","protected":"eyJhbGciOiJIUzI1NiJ9","signature":"c2ln"}`
+		if _, err := jws.ParseJSON([]byte(src), jws.WithMaxPayloadSize(7)); err != nil {
+			t.Fatalf("Parsing failed: %s", err.Error())
+		}
+		if _, err := jws.ParseJSON([]byte(src), jws.WithMaxPayloadSize(6)); err == nil {
+			t.Fatal("Parsing an oversized Payload should fail")
+		}
+		if _, err := jws.ParseJSON([]byte(src), jws.WithMaxHeaderSize(10)); err == nil {
+			t.Fatal("Parsing oversized Headers should fail")
+		}
+	})
+	t.Run("JSON Payload before decoding", func(t *testing.T) {
+		// The Payload is not valid base64url, so only its size can be checked
+		src := `{"payload":"!!!!!!!!!!","protected":"eyJhbGciOiJIUzI1NiJ9","signature":"c2ln"}`
+		_, err := jws.ParseJSON([]byte(src), jws.WithMaxPayloadSize(6))
+		if err == nil || !strings.Contains(err.Error(), "exceeds the maximum size") {
+			t.Fatalf("Expected the Payload size to be checked before decoding, got %v", err)
+		}
+
+		unencoded := `{"payload":"$.02","protected":"eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19","signature":"c2ln"}`
+		if _, err := jws.ParseJSON([]byte(unencoded), jws.WithMaxPayloadSize(4)); err != nil {
+			t.Fatalf("Parsing failed: %s", err.Error())
+		}
+		if _, err := jws.ParseJSON([]byte(unencoded), jws.WithMaxPayloadSize(3)); err == nil {
+			t.Fatal("Parsing an oversized unencoded Payload should fail")
+		}
+
+		// Escape sequences make the JSON string longer than the Payload
+		escaped := `{"payload":"\u0063GF5bG9hZA","protected":"eyJhbGciOiJIUzI1NiJ9","signature":"c2ln"}`
+		if _, err := jws.ParseJSON([]byte(escaped), jws.WithMaxPayloadSize(7)); err != nil {
+			t.Fatalf("Parsing failed: %s", err.Error())
+		}
+		// A single escape sequence must not hide the size of the rest
+		escaped = `{"payload":"\u0021!!!!!!!!!","protected":"eyJhbGciOiJIUzI1NiJ9","signature":"c2ln"}`
+		_, err = jws.ParseJSON([]byte(escaped), jws.WithMaxPayloadSize(6))
+		if err == nil || !strings.Contains(err.Error(), "exceeds the maximum size") {
+			t.Fatalf("Expected the escaped Payload size to be checked before decoding, got %v", err)
+		}
+	})
+	t.Run("JSON Headers before decoding", func(t *testing.T) {
+		// The protected Headers are not valid base64url, so only their size can be checked
+		src := `{"payload":"cGF5bG9hZA","protected":"!!!!!!!!!!!!!!!!","signature":"c2ln"}`
+		_, err := jws.ParseJSON([]byte(src), jws.WithMaxHeaderSize(10))
+		if err == nil || !strings.Contains(err.Error(), "exceeds the maximum size") {
+			t.Fatalf("Expected the protected Headers size to be checked before decoding, got %v", err)
+		}
+	})
+	t.Run("Verification", func(t *testing.T) {
+		key := []byte("Avracadabra")
+		signed, err := jws.SignWithOption([]byte(examplePayload), jwa.HS256, key)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, key, jws.WithParseOptions(jws.WithMaxSize(len(signed)))); err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, key, jws.WithParseOptions(jws.WithMaxSize(len(signed)-1))); err == nil {
+			t.Fatal("Verification of an oversized message should fail")
+		}
+	})
+}
+
 func TestAlgError(t *testing.T) {
 
 	t.Run("Unknown Algorithm", func(t *testing.T) {
@@ -130,6 +242,10 @@ func TestVerifyWithJWKSet(t *testing.T) {
 	}
 
 	_, err = jws.VerifyWithJWKSet(signature, &jwk.Set{Keys: []jwk.Key{jwkKey}})
+	if err == nil {
+		t.Fatal("Verification of a message without kid should require the fallback")
+	}
+	_, err = jws.VerifyWithJWKSet(signature, &jwk.Set{Keys: []jwk.Key{jwkKey}}, jws.WithKeyFallback())
 	if err != nil {
 		t.Fatalf("Failed to verify with JWKSet: %s", err.Error())
 	}
@@ -608,6 +724,830 @@ func TestSignErrors(t *testing.T) {
 	})
 }
 
+func TestSignWithHeaders(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+	sharedKey := []byte("Avracadabra")
+
+	t.Run("Custom headers", func(t *testing.T) {
+		var hdr jws.StandardHeaders
+		for name, value := range map[string]interface{}{
+			jws.KeyIDKey:       "my-key",
+			jws.TypeKey:        "JWT",
+			jws.ContentTypeKey: "example",
+			"zzz":              "last",
+			"aaa":              "first",
+		} {
+			if err := hdr.Set(name, value); err != nil {
+				t.Fatalf("Failed to set %s: %s", name, err.Error())
+			}
+		}
+		signed, err := jws.SignWithHeaders(payload, jwa.HS256, sharedKey, &hdr)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if hdr.Algorithm != jwa.NoValue {
+			t.Fatal("Signing should not modify the caller's Headers")
+		}
+		protected, err := base64.RawURLEncoding.DecodeString(strings.Split(string(signed), ".")[0])
+		if err != nil {
+			t.Fatalf("Failed to decode protected Headers: %s", err.Error())
+		}
+		expected := `{"alg":"HS256","cty":"example","kid":"my-key","typ":"JWT","aaa":"first","zzz":"last"}`
+		if string(protected) != expected {
+			t.Fatalf("Unexpected protected Headers: %s", protected)
+		}
+		again, err := jws.SignWithOption(payload, jwa.HS256, sharedKey, jws.WithHeaders(&hdr))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if !bytes.Equal(signed, again) {
+			t.Fatal("Headers should be serialized deterministically")
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey); err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+	})
+	t.Run("Matching algorithm", func(t *testing.T) {
+		hdr := &jws.StandardHeaders{Algorithm: jwa.HS256}
+		if _, err := jws.SignWithHeaders(payload, jwa.HS256, sharedKey, hdr); err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+	})
+	t.Run("Mismatched algorithm", func(t *testing.T) {
+		hdr := &jws.StandardHeaders{Algorithm: jwa.HS512}
+		if _, err := jws.SignWithHeaders(payload, jwa.HS256, sharedKey, hdr); err == nil {
+			t.Fatal("Signing with a mismatched alg header parameter should fail")
+		}
+	})
+	t.Run("None algorithm", func(t *testing.T) {
+		if _, err := jws.SignWithHeaders(payload, jwa.NoSignature, nil, nil); err == nil {
+			t.Fatal("Signing with the none algorithm should fail unless allowed")
+		}
+	})
+}
+
+func TestUnsecured(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	signed, err := jws.SignWithOption(payload, jwa.NoSignature, nil, jws.WithAllowNone())
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %s", err.Error())
+	}
+	if !strings.HasSuffix(string(signed), ".") {
+		t.Fatalf("Unsecured JWS should have an empty signature: %s", signed)
+	}
+
+	t.Run("Opt-in", func(t *testing.T) {
+		verified, err := jws.Verify(signed, jwa.NoSignature, sign.UnsafeAllowNone)
+		if err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+		if string(verified) != string(payload) {
+			t.Fatal("Mismatched payload")
+		}
+	})
+	t.Run("No opt-in", func(t *testing.T) {
+		if _, err := jws.SignLiteral(payload, jwa.NoSignature, nil, []byte(`{"alg":"none"}`)); err == nil {
+			t.Fatal("Signing without the UnsafeAllowNone key should fail")
+		}
+		if _, err := jws.Verify(signed, jwa.NoSignature, nil); err == nil {
+			t.Fatal("Verification without the UnsafeAllowNone key should fail")
+		}
+		if _, err := jws.Verify(signed, jwa.NoSignature, []byte("none")); err == nil {
+			t.Fatal("Verification without the UnsafeAllowNone key should fail")
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, []byte("Avracadabra")); err == nil {
+			t.Fatal("Verification of an unsecured JWS with a key should fail")
+		}
+	})
+	t.Run("Non-empty signature", func(t *testing.T) {
+		tampered := append(signed[:len(signed):len(signed)], "AAAA"...)
+		if _, err := jws.Verify(tampered, jwa.NoSignature, sign.UnsafeAllowNone); err == nil {
+			t.Fatal("Verification of a non-empty signature should fail")
+		}
+	})
+	t.Run("Strict algorithm mode", func(t *testing.T) {
+		if _, err := jws.Verify(signed, jwa.NoSignature, sign.UnsafeAllowNone, jws.WithAllowedAlgorithms(jwa.HS256)); err == nil {
+			t.Fatal("Verification should fail when none is not an allowed algorithm")
+		}
+	})
+	t.Run("Sign entry points", func(t *testing.T) {
+		verifyCompact := func(signed []byte) error {
+			_, err := jws.Verify(signed, jwa.NoSignature, sign.UnsafeAllowNone)
+			return err
+		}
+		verifyDetached := func(signed []byte) error {
+			return jws.VerifyDetached(signed, payload, jwa.NoSignature, sign.UnsafeAllowNone)
+		}
+		tests := []struct {
+			name   string
+			sign   func(options ...jws.SignOption) ([]byte, error)
+			verify func([]byte) error // nil if the none algorithm is never allowed
+		}{
+			{
+				name: "SignWithOption",
+				sign: func(options ...jws.SignOption) ([]byte, error) {
+					return jws.SignWithOption(payload, jwa.NoSignature, nil, options...)
+				},
+				verify: verifyCompact,
+			},
+			{
+				name: "SignWithHeaders",
+				sign: func(options ...jws.SignOption) ([]byte, error) {
+					return jws.SignWithHeaders(payload, jwa.NoSignature, nil, nil, options...)
+				},
+				verify: verifyCompact,
+			},
+			{
+				name: "SignFlattened",
+				sign: func(options ...jws.SignOption) ([]byte, error) {
+					return jws.SignFlattened(payload, jwa.NoSignature, nil, nil, nil, options...)
+				},
+				verify: verifyCompact,
+			},
+			{
+				name: "SignDetached",
+				sign: func(options ...jws.SignOption) ([]byte, error) {
+					return jws.SignDetached(payload, jwa.NoSignature, nil, nil, options...)
+				},
+				verify: verifyDetached,
+			},
+			{
+				name: "SignReader",
+				sign: func(options ...jws.SignOption) ([]byte, error) {
+					var buf bytes.Buffer
+					err := jws.SignReader(&buf, bytes.NewReader(payload), jwa.NoSignature, nil, []byte(`{"alg":"none"}`), options...)
+					return buf.Bytes(), err
+				},
+			},
+			{
+				name: "SignDetachedReader",
+				sign: func(options ...jws.SignOption) ([]byte, error) {
+					return jws.SignDetachedReader(bytes.NewReader(payload), jwa.NoSignature, nil, nil, options...)
+				},
+			},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				if _, err := test.sign(); err == nil {
+					t.Fatal("Signing with the none algorithm should fail unless allowed")
+				}
+				signed, err := test.sign(jws.WithAllowNone())
+				if test.verify == nil {
+					if err == nil {
+						t.Fatal("Signing with the none algorithm should fail")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Failed to sign payload: %s", err.Error())
+				}
+				if err := test.verify(signed); err != nil {
+					t.Fatalf("Failed to verify message: %s", err.Error())
+				}
+			})
+		}
+	})
+}
+
+// hmacSHA1Signer is an in-house algorithm used to test the registration
+// of signature algorithms
+type hmacSHA1Signer struct{}
+
+func (hmacSHA1Signer) Algorithm() jwa.SignatureAlgorithm {
+	return "HS1"
+}
+
+func (hmacSHA1Signer) Sign(payload []byte, key interface{}) ([]byte, error) {
+	h := hmac.New(sha1.New, key.([]byte))
+	h.Write(payload)
+	return h.Sum(nil), nil
+}
+
+func (s hmacSHA1Signer) Verify(payload, signature []byte, key interface{}) error {
+	expected, _ := s.Sign(payload, key)
+	if !hmac.Equal(signature, expected) {
+		return errors.New("failed to match signature")
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+	sharedKey := []byte("Avracadabra")
+	const alg jwa.SignatureAlgorithm = "HS1"
+
+	if _, err := jws.SignWithOption(payload, alg, sharedKey); err == nil {
+		t.Fatal("Signing with an unregistered algorithm should fail")
+	}
+	// The registrations are global, and are removed once the test is done
+	// so that it can be run again
+	if err := jwa.RegisterSignatureAlgorithm(alg, jwa.OctetSeq); err != nil {
+		t.Fatalf("Failed to register algorithm: %s", err.Error())
+	}
+	defer jwa.UnregisterSignatureAlgorithm(alg)
+	if err := sign.Register(alg, func(jwa.SignatureAlgorithm) (sign.Signer, error) {
+		return hmacSHA1Signer{}, nil
+	}); err != nil {
+		t.Fatalf("Failed to register signer: %s", err.Error())
+	}
+	defer sign.Unregister(alg)
+	if err := verify.Register(alg, func(jwa.SignatureAlgorithm) (verify.Verifier, error) {
+		return hmacSHA1Signer{}, nil
+	}); err != nil {
+		t.Fatalf("Failed to register verifier: %s", err.Error())
+	}
+	defer verify.Unregister(alg)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		signed, err := jws.SignWithOption(payload, alg, sharedKey)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		m, err := jws.ParseByte(signed)
+		if err != nil {
+			t.Fatalf("Failed to parse message: %s", err.Error())
+		}
+		if hdrAlg := m.Signatures[0].ProtectedHeaders().GetAlgorithm(); hdrAlg != alg {
+			t.Fatalf("Expected algorithm %s, got %s", alg, hdrAlg)
+		}
+		verified, err := jws.Verify(signed, alg, sharedKey, jws.WithAllowedAlgorithms(alg))
+		if err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+		if string(verified) != string(payload) {
+			t.Fatal("Mismatched payload")
+		}
+	})
+	t.Run("Duplicate algorithm", func(t *testing.T) {
+		if err := jwa.RegisterSignatureAlgorithm(alg, jwa.OctetSeq); err == nil {
+			t.Fatal("Registering an algorithm twice should fail")
+		}
+	})
+	t.Run("Built-in algorithm", func(t *testing.T) {
+		if err := sign.Register(jwa.HS256, func(jwa.SignatureAlgorithm) (sign.Signer, error) {
+			return hmacSHA1Signer{}, nil
+		}); err == nil {
+			t.Fatal("Replacing a built-in signer should fail")
+		}
+		if err := verify.Register(jwa.HS256, func(jwa.SignatureAlgorithm) (verify.Verifier, error) {
+			return hmacSHA1Signer{}, nil
+		}); err == nil {
+			t.Fatal("Replacing a built-in verifier should fail")
+		}
+	})
+	t.Run("Unregister", func(t *testing.T) {
+		signed, err := jws.SignWithOption(payload, alg, sharedKey)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		unregisters := []func(jwa.SignatureAlgorithm) error{
+			jwa.UnregisterSignatureAlgorithm,
+			sign.Unregister,
+			verify.Unregister,
+		}
+		for _, unregister := range unregisters {
+			if err := unregister(alg); err != nil {
+				t.Fatalf("Failed to unregister algorithm: %s", err.Error())
+			}
+			if err := unregister(alg); err == nil {
+				t.Fatal("Unregistering an algorithm twice should fail")
+			}
+			if err := unregister(jwa.HS256); err == nil {
+				t.Fatal("Unregistering a built-in algorithm should fail")
+			}
+		}
+		if _, err := jws.SignWithOption(payload, alg, sharedKey); err == nil {
+			t.Fatal("Signing with an unregistered algorithm should fail")
+		}
+		if _, err := jws.ParseByte(signed); err == nil {
+			t.Fatal("Parsing a message with an unregistered algorithm should fail")
+		}
+	})
+}
+
+// opaqueSigner hides the type of an in-memory key behind crypto.Signer,
+// like a key held by an HSM or a KMS
+type opaqueSigner struct {
+	signer crypto.Signer
+}
+
+func (s opaqueSigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+func (s opaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.signer.Sign(rand, digest, opts)
+}
+
+func TestCryptoSigner(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %s", err.Error())
+	}
+	var ecdsaKeys []*ecdsa.PrivateKey
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate ECDSA key: %s", err.Error())
+		}
+		ecdsaKeys = append(ecdsaKeys, key)
+	}
+
+	tests := []struct {
+		alg jwa.SignatureAlgorithm
+		key crypto.Signer
+	}{
+		{alg: jwa.RS256, key: rsaKey},
+		{alg: jwa.PS384, key: rsaKey},
+		{alg: jwa.ES256, key: ecdsaKeys[0]},
+		{alg: jwa.ES384, key: ecdsaKeys[1]},
+		{alg: jwa.ES512, key: ecdsaKeys[2]},
+	}
+	for _, test := range tests {
+		t.Run(test.alg.String(), func(t *testing.T) {
+			signed, err := jws.SignWithOption(payload, test.alg, opaqueSigner{test.key})
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			verified, err := jws.Verify(signed, test.alg, test.key.Public())
+			if err != nil {
+				t.Fatalf("Failed to verify message: %s", err.Error())
+			}
+			if string(verified) != string(payload) {
+				t.Fatal("Mismatched payload")
+			}
+		})
+	}
+	t.Run("Mismatched public key", func(t *testing.T) {
+		if _, err := jws.SignWithOption(payload, jwa.ES256, opaqueSigner{rsaKey}); err == nil {
+			t.Fatal("Signing with an RSA signer should fail for ES256")
+		}
+		if _, err := jws.SignWithOption(payload, jwa.RS256, opaqueSigner{ecdsaKeys[0]}); err == nil {
+			t.Fatal("Signing with an ECDSA signer should fail for RS256")
+		}
+	})
+}
+
+func TestEdDSA(t *testing.T) {
+	// https://tools.ietf.org/html/rfc8037#appendix-A.4
+	const jwkSrc = `{"kty":"OKP","crv":"Ed25519","d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+	const expected = `eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc.hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg`
+	payload := []byte("Example of Ed25519 signing")
+
+	set, err := jwk.ParseString(jwkSrc)
+	if err != nil {
+		t.Fatalf("Failed to parse JWK: %s", err.Error())
+	}
+	signed, err := jws.SignWithJWK(payload, set.Keys[0])
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %s", err.Error())
+	}
+	if string(signed) != expected {
+		t.Fatalf("Unexpected compact serialization: %s", signed)
+	}
+
+	privateKey, err := set.Keys[0].Materialize()
+	if err != nil {
+		t.Fatalf("Failed to materialize key: %s", err.Error())
+	}
+	publicKey, err := jwk.GetPublicKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to get public key: %s", err.Error())
+	}
+	verified, err := jws.Verify(signed, jwa.EdDSA, publicKey, jws.WithAllowedAlgorithms(jwa.EdDSA))
+	if err != nil {
+		t.Fatalf("Failed to verify message: %s", err.Error())
+	}
+	if string(verified) != string(payload) {
+		t.Fatal("Mismatched payload")
+	}
+	if _, err := jws.Verify(signed, jwa.EdDSA, publicKey, jws.WithAllowedAlgorithms(jwa.ES256)); err == nil {
+		t.Fatal("Verification should fail when EdDSA is not an allowed algorithm")
+	}
+	tampered := []byte(strings.Replace(expected, "RXhh", "RXha", 1))
+	if _, err := jws.Verify(tampered, jwa.EdDSA, publicKey); err == nil {
+		t.Fatal("Verification of a tampered payload should fail")
+	}
+}
+
+func TestAsymmetricAlgorithms(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	ecdsaKey := func(curve elliptic.Curve) func() (crypto.Signer, error) {
+		return func() (crypto.Signer, error) {
+			return ecdsa.GenerateKey(curve, rand.Reader)
+		}
+	}
+	mldsaKey := func(params *mldsa.Parameters) func() (crypto.Signer, error) {
+		return func() (crypto.Signer, error) {
+			return mldsa.GenerateKey(params, rand.Reader)
+		}
+	}
+	tests := []struct {
+		alg         jwa.SignatureAlgorithm
+		generateKey func() (crypto.Signer, error)
+		size        int
+		// A curve the algorithm must not be used with, and the algorithm
+		// that curve is bound to
+		wrongCurve elliptic.Curve
+		wrongAlg   jwa.SignatureAlgorithm
+	}{
+		{alg: jwa.ES256K, generateKey: ecdsaKey(curves.Secp256k1()), size: 64, wrongCurve: elliptic.P256(), wrongAlg: jwa.ES256},
+		{alg: jwa.ESB256, generateKey: ecdsaKey(curves.BrainpoolP256r1()), size: 64, wrongCurve: elliptic.P256(), wrongAlg: jwa.ES256},
+		{alg: jwa.ESB256, generateKey: ecdsaKey(curves.BrainpoolP256r1()), size: 64, wrongCurve: curves.Secp256k1(), wrongAlg: jwa.ES256K},
+		{alg: jwa.ESB384, generateKey: ecdsaKey(curves.BrainpoolP384r1()), size: 96, wrongCurve: elliptic.P384(), wrongAlg: jwa.ES384},
+		{alg: jwa.ESB512, generateKey: ecdsaKey(curves.BrainpoolP512r1()), size: 128, wrongCurve: elliptic.P521(), wrongAlg: jwa.ES512},
+		{alg: jwa.MLDSA44, generateKey: mldsaKey(mldsa.MLDSA44()), size: mldsa.MLDSA44().SignatureSize()},
+		{alg: jwa.MLDSA65, generateKey: mldsaKey(mldsa.MLDSA65()), size: mldsa.MLDSA65().SignatureSize()},
+		{alg: jwa.MLDSA87, generateKey: mldsaKey(mldsa.MLDSA87()), size: mldsa.MLDSA87().SignatureSize()},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(string(test.alg), func(t *testing.T) {
+			privateKey, err := test.generateKey()
+			if err != nil {
+				t.Fatalf("Failed to generate key: %s", err.Error())
+			}
+			key, err := jwk.New(privateKey)
+			if err != nil {
+				t.Fatalf("Failed to create JWK: %s", err.Error())
+			}
+			signed, err := jws.SignWithJWK(payload, key)
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			m, err := jws.ParseByte(signed)
+			if err != nil {
+				t.Fatalf("Failed to parse message: %s", err.Error())
+			}
+			if alg := m.Signatures[0].ProtectedHeaders().GetAlgorithm(); alg != test.alg {
+				t.Fatalf("Expected algorithm %s, got %s", test.alg, alg)
+			}
+			if len(m.Signatures[0].Signature) != test.size {
+				t.Fatalf("Expected a %d octets signature, got %d", test.size, len(m.Signatures[0].Signature))
+			}
+
+			publicKey, err := jwk.New(privateKey.Public())
+			if err != nil {
+				t.Fatalf("Failed to create JWK: %s", err.Error())
+			}
+			buf, err := json.Marshal(publicKey)
+			if err != nil {
+				t.Fatalf("Failed to marshal JWK: %s", err.Error())
+			}
+			set, err := jwk.ParseBytes(buf)
+			if err != nil {
+				t.Fatalf("Failed to parse JWK: %s", err.Error())
+			}
+			verified, err := jws.VerifyWithJWKSet(signed, set, jws.WithKeyFallback(), jws.WithAllowedAlgorithms(test.alg))
+			if err != nil {
+				t.Fatalf("Failed to verify message: %s", err.Error())
+			}
+			if string(verified) != string(payload) {
+				t.Fatal("Mismatched payload")
+			}
+
+			otherKey, err := test.generateKey()
+			if err != nil {
+				t.Fatalf("Failed to generate key: %s", err.Error())
+			}
+			if _, err := jws.Verify(signed, test.alg, otherKey.Public()); err == nil {
+				t.Fatal("Verification with another key should fail")
+			}
+
+			if test.wrongCurve == nil {
+				return
+			}
+			wrongKey, err := ecdsa.GenerateKey(test.wrongCurve, rand.Reader)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %s", err.Error())
+			}
+			if _, err := jws.SignWithOption(payload, test.alg, wrongKey); err == nil {
+				t.Fatalf("Signing with a %s key should fail", test.wrongCurve.Params().Name)
+			}
+			// A signature made with the algorithm of the wrong curve, under
+			// Headers claiming the algorithm under test
+			forged, err := jws.SignLiteral(payload, test.wrongAlg, wrongKey, []byte(`{"alg":"`+test.alg.String()+`"}`))
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			if _, err := jws.Verify(forged, test.wrongAlg, &wrongKey.PublicKey); err != nil {
+				t.Fatalf("Failed to verify message: %s", err.Error())
+			}
+			if _, err := jws.Verify(forged, test.alg, &wrongKey.PublicKey); err == nil {
+				t.Fatalf("Verification with a %s key should fail", test.wrongCurve.Params().Name)
+			}
+		})
+	}
+}
+
+func TestESB256(t *testing.T) {
+	// The public key of https://tools.ietf.org/html/rfc7027#appendix-A.1,
+	// and a message signed with its private key by OpenSSL
+	const jwkSrc = `{"kty":"EC","crv":"BP-256","x":"RBBukT-SvAKhcF2ZU6hBTbleGqpJ6B2ehfkpqOMQC-U","y":"irSEbxHKzLc85Jy90SD1qQCmn9MsJyIj94nvEOsIm9w"}`
+	const signed = `eyJhbGciOiJFU0IyNTYifQ.TG9yZW0gaXBzdW0.PRo1TRw15kH-HyYTXdJTZbRdW2Okv6-L8o6An1gTMmYK4SHy0G_HHLa8qev3QEBTvAZlkX5ZTcdj_O41F3DPiw`
+
+	set, err := jwk.ParseString(jwkSrc)
+	if err != nil {
+		t.Fatalf("Failed to parse JWK: %s", err.Error())
+	}
+	verified, err := jws.VerifyWithJWK([]byte(signed), set.Keys[0], jws.WithAllowedAlgorithms(jwa.ESB256))
+	if err != nil {
+		t.Fatalf("Failed to verify message: %s", err.Error())
+	}
+	if string(verified) != "Lorem ipsum" {
+		t.Fatal("Mismatched payload")
+	}
+	tampered := strings.Replace(signed, "TG9y", "TG9z", 1)
+	if _, err := jws.VerifyWithJWK([]byte(tampered), set.Keys[0]); err == nil {
+		t.Fatal("Verification of a tampered payload should fail")
+	}
+}
+
+func TestES256K(t *testing.T) {
+	// A secp256k1 key generated by OpenSSL, and a message signed with its
+	// private key by OpenSSL
+	const jwkSrc = `{"kty":"EC","crv":"secp256k1","x":"mNpNM9zG4wV9kGW82xd_4JXV5StX4fVfKWlgObmHkBg","y":"ErP4X__PYbO4veuf_ji7khkCw85BJvTgKoasdVB6fJU"}`
+	const signed = `eyJhbGciOiJFUzI1NksifQ.TG9yZW0gaXBzdW0.U68ylUpoXlYhqcTM-SQZb-qMPWQ6HEwLXtGeilgFWZ4AV2gq6K_o5-40EVmeru3m2L5LYusFEDK595O9eG3G9g`
+
+	set, err := jwk.ParseString(jwkSrc)
+	if err != nil {
+		t.Fatalf("Failed to parse JWK: %s", err.Error())
+	}
+	verified, err := jws.VerifyWithJWK([]byte(signed), set.Keys[0], jws.WithAllowedAlgorithms(jwa.ES256K))
+	if err != nil {
+		t.Fatalf("Failed to verify message: %s", err.Error())
+	}
+	if string(verified) != "Lorem ipsum" {
+		t.Fatal("Mismatched payload")
+	}
+	tampered := strings.Replace(signed, "TG9y", "TG9z", 1)
+	if _, err := jws.VerifyWithJWK([]byte(tampered), set.Keys[0]); err == nil {
+		t.Fatal("Verification of a tampered payload should fail")
+	}
+}
+
+func TestMLDSAParameterSets(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	privateKey, err := mldsa.GenerateKey(mldsa.MLDSA44(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	if _, err := jws.SignWithOption(payload, jwa.MLDSA65, privateKey); err == nil {
+		t.Fatal("Signing with a key of another parameter set should fail")
+	}
+	signed, err := jws.SignWithOption(payload, jwa.MLDSA44, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %s", err.Error())
+	}
+	if _, err := jws.Verify(signed, jwa.MLDSA65, privateKey.PublicKey()); err == nil {
+		t.Fatal("Verification with a key of another parameter set should fail")
+	}
+}
+
+func TestDeterministicECDSA(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	tests := []struct {
+		alg   jwa.SignatureAlgorithm
+		curve elliptic.Curve
+	}{
+		{alg: jwa.ES256, curve: elliptic.P256()},
+		{alg: jwa.ES384, curve: elliptic.P384()},
+		{alg: jwa.ES512, curve: elliptic.P521()},
+		{alg: jwa.ES256K, curve: curves.Secp256k1()},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(string(test.alg), func(t *testing.T) {
+			privateKey, err := ecdsa.GenerateKey(test.curve, rand.Reader)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %s", err.Error())
+			}
+			signed, err := jws.SignWithOption(payload, test.alg, privateKey, jws.WithDeterministicECDSA())
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			again, err := jws.SignWithOption(payload, test.alg, privateKey, jws.WithDeterministicECDSA())
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			if string(signed) != string(again) {
+				t.Fatal("Deterministic signatures should be equal")
+			}
+			verified, err := jws.Verify(signed, test.alg, &privateKey.PublicKey)
+			if err != nil {
+				t.Fatalf("Failed to verify message: %s", err.Error())
+			}
+			if string(verified) != string(payload) {
+				t.Fatal("Mismatched payload")
+			}
+
+			randomized, err := jws.SignWithOption(payload, test.alg, privateKey)
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			if string(signed) == string(randomized) {
+				t.Fatal("Signatures should be randomized by default")
+			}
+		})
+	}
+	t.Run("Serializations", func(t *testing.T) {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %s", err.Error())
+		}
+		deterministic := jws.WithDeterministicECDSA()
+		hdrBuf := []byte(`{"alg":"ES256"}`)
+		serializations := map[string]func() ([]byte, error){
+			"Flattened": func() ([]byte, error) {
+				return jws.SignFlattened(payload, jwa.ES256, privateKey, nil, nil, deterministic)
+			},
+			"General": func() ([]byte, error) {
+				msg, err := jws.SignMulti(payload, jws.SignerConfig{
+					Algorithm:          jwa.ES256,
+					Key:                privateKey,
+					DeterministicECDSA: true,
+				})
+				if err != nil {
+					return nil, err
+				}
+				return jws.SerializeJSON(msg)
+			},
+			"Detached": func() ([]byte, error) {
+				return jws.SignDetached(payload, jwa.ES256, privateKey, nil, deterministic)
+			},
+			"Reader": func() ([]byte, error) {
+				var buf bytes.Buffer
+				err := jws.SignReader(&buf, bytes.NewReader(payload), jwa.ES256, privateKey, hdrBuf, deterministic)
+				return buf.Bytes(), err
+			},
+			"Detached reader": func() ([]byte, error) {
+				return jws.SignDetachedReader(bytes.NewReader(payload), jwa.ES256, privateKey, nil, deterministic)
+			},
+		}
+		for name, serialize := range serializations {
+			serialize := serialize
+			t.Run(name, func(t *testing.T) {
+				signed, err := serialize()
+				if err != nil {
+					t.Fatalf("Failed to sign payload: %s", err.Error())
+				}
+				again, err := serialize()
+				if err != nil {
+					t.Fatalf("Failed to sign payload: %s", err.Error())
+				}
+				if string(signed) != string(again) {
+					t.Fatal("Deterministic signatures should be equal")
+				}
+			})
+		}
+		t.Run("Verification", func(t *testing.T) {
+			flattened, err := serializations["Flattened"]()
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			if _, err := jws.Verify(flattened, jwa.ES256, &privateKey.PublicKey); err != nil {
+				t.Fatalf("Failed to verify message: %s", err.Error())
+			}
+			detached, err := serializations["Detached"]()
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			streamed, err := serializations["Detached reader"]()
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			if string(detached) != string(streamed) {
+				t.Fatal("Streamed and in-memory signatures should be equal")
+			}
+			if err := jws.VerifyDetached(detached, payload, jwa.ES256, &privateKey.PublicKey); err != nil {
+				t.Fatalf("Failed to verify detached payload: %s", err.Error())
+			}
+		})
+	})
+	t.Run("crypto.Signer", func(t *testing.T) {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %s", err.Error())
+		}
+		if _, err := jws.SignWithOption(payload, jwa.ES256, opaqueSigner{privateKey}, jws.WithDeterministicECDSA()); err == nil {
+			t.Fatal("Deterministic signing without access to the private key should fail")
+		}
+	})
+	t.Run("Other algorithms", func(t *testing.T) {
+		signed, err := jws.SignWithOption(payload, jwa.HS256, []byte("Avracadabra"), jws.WithDeterministicECDSA())
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, []byte("Avracadabra")); err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+	})
+}
+
+func TestSignWithJWK(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	newKey := func(t *testing.T, rawKey interface{}, params map[string]interface{}) jwk.Key {
+		t.Helper()
+		key, err := jwk.New(rawKey)
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		for name, value := range params {
+			if err := key.Set(name, value); err != nil {
+				t.Fatalf("Failed to set %s: %s", name, err.Error())
+			}
+		}
+		return key
+	}
+	protectedHeaders := func(t *testing.T, signed []byte) *jws.StandardHeaders {
+		t.Helper()
+		m, err := jws.ParseByte(signed)
+		if err != nil {
+			t.Fatalf("Failed to parse message: %s", err.Error())
+		}
+		return m.Signatures[0].Protected.(*jws.StandardHeaders)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %s", err.Error())
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %s", err.Error())
+	}
+
+	t.Run("Default algorithms", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			rawKey interface{}
+			pubKey interface{}
+			alg    jwa.SignatureAlgorithm
+		}{
+			{name: "RSA", rawKey: rsaKey, pubKey: &rsaKey.PublicKey, alg: jwa.RS256},
+			{name: "ECDSA", rawKey: ecdsaKey, pubKey: &ecdsaKey.PublicKey, alg: jwa.ES384},
+			{name: "Short secret", rawKey: make([]byte, 32), pubKey: make([]byte, 32), alg: jwa.HS256},
+			{name: "Long secret", rawKey: make([]byte, 64), pubKey: make([]byte, 64), alg: jwa.HS512},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				signed, err := jws.SignWithJWK(payload, newKey(t, test.rawKey, nil))
+				if err != nil {
+					t.Fatalf("Failed to sign payload: %s", err.Error())
+				}
+				if alg := protectedHeaders(t, signed).Algorithm; alg != test.alg {
+					t.Fatalf("Expected algorithm %s, got %s", test.alg, alg)
+				}
+				if _, err := jws.Verify(signed, test.alg, test.pubKey); err != nil {
+					t.Fatalf("Failed to verify message: %s", err.Error())
+				}
+			})
+		}
+	})
+	t.Run("Key algorithm and ID", func(t *testing.T) {
+		key := newKey(t, rsaKey, map[string]interface{}{
+			jwk.AlgorithmKey: jwa.PS384,
+			jwk.KeyIDKey:     "my-key",
+		})
+		signed, err := jws.SignWithJWK(payload, key, jws.WithHeaders(&jws.StandardHeaders{Type: "JWT"}))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		hdr := protectedHeaders(t, signed)
+		if hdr.Algorithm != jwa.PS384 || hdr.KeyID != "my-key" || hdr.Type != "JWT" {
+			t.Fatalf("Unexpected protected Headers: %+v", hdr)
+		}
+		if _, err := jws.Verify(signed, jwa.PS384, &rsaKey.PublicKey); err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+	})
+	t.Run("Mismatched key ID", func(t *testing.T) {
+		key := newKey(t, rsaKey, map[string]interface{}{jwk.KeyIDKey: "my-key"})
+		if _, err := jws.SignWithJWK(payload, key, jws.WithHeaders(&jws.StandardHeaders{KeyID: "other"})); err == nil {
+			t.Fatal("Signing with a mismatched kid header parameter should fail")
+		}
+	})
+	t.Run("Encryption key", func(t *testing.T) {
+		key := newKey(t, rsaKey, map[string]interface{}{jwk.KeyUsageKey: "enc"})
+		if _, err := jws.SignWithJWK(payload, key); err == nil {
+			t.Fatal("Signing with an encryption key should fail")
+		}
+	})
+	t.Run("Verification key", func(t *testing.T) {
+		key := newKey(t, rsaKey, map[string]interface{}{jwk.KeyOpsKey: jwk.KeyOperationList{jwk.KeyOpVerify}})
+		if _, err := jws.SignWithJWK(payload, key); err == nil {
+			t.Fatal("Signing with a key restricted to verification should fail")
+		}
+	})
+}
+
 func TestVerifyErrors(t *testing.T) {
 
 	t.Run("Invalid compact serialization", func(t *testing.T) {
@@ -670,3 +1610,57 @@ func TestSplitCompactLongStrings(t *testing.T) {
 		}
 	})
 }
+
+func TestDetached(t *testing.T) {
+	payload := []byte("Hello, World!")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		signed, err := jws.SignDetached(payload, jwa.RS256, key, nil)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		parts, err := jws.SplitCompact(string(signed))
+		if err != nil {
+			t.Fatalf("Failed to split compact serialization: %s", err.Error())
+		}
+		if parts[1] != "" {
+			t.Fatal("Payload should be detached")
+		}
+		if err := jws.VerifyDetached(signed, payload, jwa.RS256, &key.PublicKey); err != nil {
+			t.Fatalf("Failed to verify detached payload: %s", err.Error())
+		}
+		if err := jws.VerifyDetached(signed, []byte("Goodbye, World!"), jwa.RS256, &key.PublicKey); err == nil {
+			t.Fatal("Verification of a different payload should fail")
+		}
+	})
+	t.Run("Custom Headers", func(t *testing.T) {
+		hdrBuf := []byte(`{"alg":"RS256","kid":"my-key"}`)
+		signed, err := jws.SignDetached(payload, jwa.RS256, key, hdrBuf)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		msg, err := jws.ParseByte(signed)
+		if err != nil {
+			t.Fatalf("Failed to parse detached JWS: %s", err.Error())
+		}
+		if kid, ok := msg.GetSignatures()[0].ProtectedHeaders().Get(jws.KeyIDKey); !ok || kid != "my-key" {
+			t.Fatal("kid does not match")
+		}
+		if err := jws.VerifyDetached(signed, payload, jwa.RS256, &key.PublicKey); err != nil {
+			t.Fatalf("Failed to verify detached payload: %s", err.Error())
+		}
+	})
+	t.Run("Attached payload", func(t *testing.T) {
+		signed, err := jws.SignWithOption(payload, jwa.RS256, key)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if err := jws.VerifyDetached(signed, payload, jwa.RS256, &key.PublicKey); err == nil {
+			t.Fatal("Verification of an attached payload should fail")
+		}
+	})
+}
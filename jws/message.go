@@ -1,5 +1,16 @@
 package jws
 
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jwk"
+)
+
 // PublicHeaders returns the public headers in a JWS
 func (s Signature) PublicHeaders() Headers {
 	return s.Headers
@@ -10,6 +21,12 @@ func (s Signature) ProtectedHeaders() Headers {
 	return s.Protected
 }
 
+// RawProtectedHeaders returns the protected headers exactly as they
+// were encoded in the JWS, i.e. the bytes that were actually signed
+func (s Signature) RawProtectedHeaders() []byte {
+	return s.rawProtected
+}
+
 // GetSignature returns the signature in a JWS
 func (s Signature) GetSignature() []byte {
 	return s.Signature
@@ -24,3 +41,109 @@ func (m Message) GetPayload() []byte {
 func (m Message) GetSignatures() []*Signature {
 	return m.Signatures
 }
+
+// VerifiedAlgorithm returns the algorithm that verified the message, or
+// an empty value if the message was not returned by a verification
+func (m Message) VerifiedAlgorithm() jwa.SignatureAlgorithm {
+	if m.verified == nil {
+		return jwa.NoValue
+	}
+	return m.verified.alg
+}
+
+// VerifiedKey returns the key that verified the message, or nil if the
+// message was not returned by a verification
+func (m Message) VerifiedKey() interface{} {
+	if m.verified == nil {
+		return nil
+	}
+	return m.verified.key
+}
+
+// VerifiedJWK returns the JWK that verified the message, or nil if the
+// message was not verified with a JWK
+func (m Message) VerifiedJWK() jwk.Key {
+	if m.verified == nil {
+		return nil
+	}
+	return m.verified.source
+}
+
+// MarshalJSON serializes the message using the general JSON serialization
+func (m Message) MarshalJSON() ([]byte, error) {
+	return SerializeJSON(&m)
+}
+
+// UnmarshalJSON parses a message serialized using either the general or
+// the flattened JSON serialization
+func (m *Message) UnmarshalJSON(buf []byte) error {
+	msg, err := ParseJSON(buf)
+	if err != nil {
+		return err
+	}
+	*m = *msg
+	return nil
+}
+
+// Compact serializes a message with exactly one signature in compact
+// serialization format. The signature must not have unprotected Headers,
+// as they cannot be represented in this format.
+func (m Message) Compact() ([]byte, error) {
+
+	if len(m.Signatures) != 1 {
+		return nil, errors.New(`compact serialization requires exactly one signature`)
+	}
+	sig := m.Signatures[0]
+	if sig.Headers != nil {
+		return nil, errors.New(`compact serialization does not support unprotected Headers`)
+	}
+	protected, err := sig.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(protected) == 0 {
+		return nil, errors.New(`compact serialization requires protected Headers`)
+	}
+	b64, err := isBase64Payload(sig.Protected)
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid Payload encoding`)
+	}
+	// https://tools.ietf.org/html/rfc7797#section-5.2
+	if !b64 && bytes.IndexByte(m.Payload, '.') >= 0 {
+		return nil, errors.New(`unencoded Payload must not contain '.' in compact serialization`)
+	}
+
+	compactSerialization := strings.Join(
+		[]string{
+			base64.RawURLEncoding.EncodeToString(protected),
+			string(encodePayload(m.Payload, b64)),
+			base64.RawURLEncoding.EncodeToString(sig.Signature),
+		}, ".",
+	)
+	return []byte(compactSerialization), nil
+}
+
+// MarshalJSON serializes the signature as a member of the "signatures"
+// array of the general JSON serialization
+func (s Signature) MarshalJSON() ([]byte, error) {
+	rawSig, err := s.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rawSig)
+}
+
+// UnmarshalJSON parses a member of the "signatures" array of the general
+// JSON serialization
+func (s *Signature) UnmarshalJSON(buf []byte) error {
+	var rawSig rawSignatureJSON
+	if err := json.Unmarshal(buf, &rawSig); err != nil {
+		return errors.Wrap(err, `failed to unmarshal signature`)
+	}
+	sig, err := parseSignatureJSON(&rawSig, newParseConfig(nil))
+	if err != nil {
+		return err
+	}
+	*s = *sig
+	return nil
+}
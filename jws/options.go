@@ -0,0 +1,228 @@
+package jws
+
+import (
+	"crypto"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jwk"
+)
+
+// VerifyOption configures the verification of JWS messages
+type VerifyOption func(*verifyConfig)
+
+// verifyConfig holds the settings used while verifying a JWS message
+type verifyConfig struct {
+	criticalHandlers  map[string]CriticalHandler
+	allowedAlgorithms map[jwa.SignatureAlgorithm]struct{}
+	keyAccept         KeyAcceptFunc
+	keyFallback       bool
+	parseOptions      []ParseOption
+	thumbprintHash    crypto.Hash
+	thumbprints       map[string]struct{}
+	trustedKey        KeyAcceptFunc
+	x509KeyUsages     []x509.ExtKeyUsage
+}
+
+// KeyAcceptFunc decides whether a key of a JWK set may be used to verify
+// a JWS message
+type KeyAcceptFunc func(jwk.Key) bool
+
+// newVerifyConfig applies the given options to a default configuration
+func newVerifyConfig(options []VerifyOption) *verifyConfig {
+	cfg := &verifyConfig{
+		criticalHandlers: map[string]CriticalHandler{},
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+	return cfg
+}
+
+// WithCriticalHandler registers `handler` to process the extension header
+// parameter `name` whenever it is listed in the "crit" header parameter.
+// Messages listing critical extensions that have no handler are rejected.
+func WithCriticalHandler(name string, handler CriticalHandler) VerifyOption {
+	return func(cfg *verifyConfig) {
+		cfg.criticalHandlers[name] = handler
+	}
+}
+
+// WithAllowedAlgorithms binds the verification to the "alg" protected
+// header parameter. Signatures are rejected when their protected Headers
+// do not declare an algorithm, when the declared algorithm is not one of
+// `algs`, or when it differs from the algorithm used to verify them or
+// does not fit the type of the key. This prevents algorithm substitution,
+// such as a public RSA key being used as an HMAC secret.
+func WithAllowedAlgorithms(algs ...jwa.SignatureAlgorithm) VerifyOption {
+	return func(cfg *verifyConfig) {
+		cfg.allowedAlgorithms = map[jwa.SignatureAlgorithm]struct{}{}
+		for _, alg := range algs {
+			cfg.allowedAlgorithms[alg] = struct{}{}
+		}
+	}
+}
+
+// WithKeyAccept restricts the keys of a JWK set that are considered when
+// verifying a JWS message to the ones approved by `accept`
+func WithKeyAccept(accept KeyAcceptFunc) VerifyOption {
+	return func(cfg *verifyConfig) {
+		cfg.keyAccept = accept
+	}
+}
+
+// WithKeyFallback allows verifying a JWS message with every usable key of
+// a JWK set when the message has no "kid" header parameter, or when no
+// key of the set matches it. Without this option such messages are
+// rejected.
+func WithKeyFallback() VerifyOption {
+	return func(cfg *verifyConfig) {
+		cfg.keyFallback = true
+	}
+}
+
+// WithTrustedThumbprints trusts the keys embedded in the "jwk" header
+// parameter whose JWK Thumbprint, computed using `hash` as described in
+// https://tools.ietf.org/html/rfc7638, is one of `thumbprints`
+func WithTrustedThumbprints(hash crypto.Hash, thumbprints ...[]byte) VerifyOption {
+	return func(cfg *verifyConfig) {
+		cfg.thumbprintHash = hash
+		cfg.thumbprints = map[string]struct{}{}
+		for _, thumbprint := range thumbprints {
+			cfg.thumbprints[string(thumbprint)] = struct{}{}
+		}
+	}
+}
+
+// WithTrustedKeyFunc trusts the keys embedded in the "jwk" header
+// parameter that are approved by `trust`
+func WithTrustedKeyFunc(trust KeyAcceptFunc) VerifyOption {
+	return func(cfg *verifyConfig) {
+		cfg.trustedKey = trust
+	}
+}
+
+// WithX509KeyUsages requires the certificate chain found in the "x5c"
+// header parameter to be valid for one of the extended key `usages`.
+// By default any extended key usage is accepted.
+func WithX509KeyUsages(usages ...x509.ExtKeyUsage) VerifyOption {
+	return func(cfg *verifyConfig) {
+		cfg.x509KeyUsages = usages
+	}
+}
+
+// WithParseOptions applies `options` when parsing the JWS message that
+// is being verified
+func WithParseOptions(options ...ParseOption) VerifyOption {
+	return func(cfg *verifyConfig) {
+		cfg.parseOptions = append(cfg.parseOptions, options...)
+	}
+}
+
+// ParseOption configures the parsing of JWS messages
+type ParseOption func(*parseConfig)
+
+// parseConfig holds the limits enforced while parsing a JWS message.
+// A zero limit means that the size is not limited.
+type parseConfig struct {
+	maxSize        int
+	maxHeaderSize  int
+	maxPayloadSize int
+}
+
+// newParseConfig applies the given options to a default configuration
+func newParseConfig(options []ParseOption) *parseConfig {
+	cfg := &parseConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	return cfg
+}
+
+// WithMaxSize limits the size in bytes of the serialized JWS message
+func WithMaxSize(size int) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.maxSize = size
+	}
+}
+
+// WithMaxHeaderSize limits the size in bytes of each of the decoded
+// protected and unprotected Headers
+func WithMaxHeaderSize(size int) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.maxHeaderSize = size
+	}
+}
+
+// WithMaxPayloadSize limits the size in bytes of the decoded Payload
+func WithMaxPayloadSize(size int) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.maxPayloadSize = size
+	}
+}
+
+// checkSize rejects `size` bytes of `what` when they exceed the limit
+// `max`, unless the size is not limited
+func checkSize(what string, size, max int) error {
+	if max > 0 && size > max {
+		return errors.Errorf(`%s of %d bytes exceeds the maximum size of %d bytes`, what, size, max)
+	}
+	return nil
+}
+
+// SignOption configures the signing of JWS messages
+type SignOption func(*signConfig)
+
+// signConfig holds the settings used while signing a JWS message
+type signConfig struct {
+	headers            Headers
+	allowNone          bool
+	deterministicECDSA bool
+}
+
+// newSignConfig applies the given options to a default configuration
+func newSignConfig(options []SignOption) *signConfig {
+	cfg := &signConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	return cfg
+}
+
+// WithHeaders signs the message with the given protected Headers. The
+// "alg" header parameter is set when missing, and must otherwise match
+// the algorithm used to sign.
+func WithHeaders(hdr Headers) SignOption {
+	return func(cfg *signConfig) {
+		cfg.headers = hdr
+	}
+}
+
+// WithAllowNone allows producing unsecured messages with the "none"
+// algorithm, which is rejected by default. The key is then ignored.
+// Unsecured messages are only verified when sign.UnsafeAllowNone is given
+// as the key. SignReader and SignDetachedReader reject the algorithm
+// regardless, as they cannot sign unsecured messages.
+func WithAllowNone() SignOption {
+	return func(cfg *signConfig) {
+		cfg.allowNone = true
+	}
+}
+
+// WithDeterministicECDSA makes ECDSA signatures reproducible, by deriving
+// their nonce from the private key and the signed content as described in
+// https://tools.ietf.org/html/rfc6979 rather than reading it from
+// crypto/rand. The key must then be an *ecdsa.PrivateKey. Other algorithms
+// are not affected. Use the DeterministicECDSA field of SignerConfig to the
+// same effect with `SignMulti`.
+//
+// The deterministic signer is not constant time, and may leak timing
+// information about the key, as described for sign.NewDeterministicECDSA.
+// Only use this option when reproducible signatures are required, such as
+// for golden files in tests.
+func WithDeterministicECDSA() SignOption {
+	return func(cfg *signConfig) {
+		cfg.deterministicECDSA = true
+	}
+}
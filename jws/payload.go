@@ -0,0 +1,136 @@
+package jws
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/buffer"
+)
+
+// isBase64Payload reports whether the Payload is base64url encoded, as
+// determined by the "b64" header parameter described in
+// https://tools.ietf.org/html/rfc7797#section-3. Whenever the parameter
+// is used, it must also be listed in the "crit" header parameter.
+func isBase64Payload(hdr Headers) (bool, error) {
+	if hdr == nil {
+		return true, nil
+	}
+	v, ok := hdr.Get(Base64Key)
+	if !ok {
+		return true, nil
+	}
+	b64, ok := v.(bool)
+	if !ok {
+		return false, errors.Errorf(`invalid value for %s key: %T`, Base64Key, v)
+	}
+	if v, ok := hdr.Get(CriticalKey); ok {
+		crit, ok := v.([]string)
+		if !ok {
+			return false, errors.Errorf(`invalid value for %s key: %T`, CriticalKey, v)
+		}
+		for _, name := range crit {
+			if name == Base64Key {
+				return b64, nil
+			}
+		}
+	}
+	return false, errors.Errorf(`%s header parameter must be listed as critical`, Base64Key)
+}
+
+// isBase64Payload reports whether the Payload of the message is base64url
+// encoded. All signatures must agree on the encoding of the Payload.
+func (m *Message) isBase64Payload() (bool, error) {
+	b64 := true
+	for i, sig := range m.Signatures {
+		v, err := isBase64Payload(sig.Protected)
+		if err != nil {
+			return false, err
+		}
+		if i > 0 && v != b64 {
+			return false, errors.Errorf(`all signatures must use the same %s value`, Base64Key)
+		}
+		b64 = v
+	}
+	return b64, nil
+}
+
+// encodePayload returns the representation of the Payload used in the
+// JWS Signing Input and in the compact serialization
+func encodePayload(payload []byte, b64 bool) []byte {
+	if !b64 {
+		return payload
+	}
+	enc := base64.RawURLEncoding
+	out := make([]byte, enc.EncodedLen(len(payload)))
+	enc.Encode(out, payload)
+	return out
+}
+
+// signingInput computes the JWS Signing Input from the encoded Headers and the Payload
+func signingInput(encodedHdr string, payload []byte, b64 bool) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(encodedHdr)
+	buf.WriteByte('.')
+	buf.Write(encodePayload(payload, b64))
+	return buf.Bytes()
+}
+
+// encodeJSONPayload returns the representation of the Payload used in
+// the JSON serialization formats
+func encodeJSONPayload(payload []byte, b64 bool) (json.RawMessage, error) {
+	if b64 {
+		return json.Marshal(buffer.Buffer(payload))
+	}
+	if !utf8.Valid(payload) {
+		return nil, errors.New(`unencoded Payload must be valid UTF-8 in JSON serialization`)
+	}
+	return json.Marshal(string(payload))
+}
+
+// jsonPayloadSize returns a lower bound of the size of the Payload found
+// in the JSON serialization formats, without decoding it. Every character
+// of the JSON string decodes to at least one byte, and so does every
+// escape sequence, which is counted once whatever its length.
+func jsonPayloadSize(raw json.RawMessage, b64 bool) int {
+	if len(raw) < 2 {
+		return 0
+	}
+	size := 0
+	for i := 1; i < len(raw)-1; i++ { // Quotes
+		if raw[i] == '\\' {
+			if raw[i+1] == 'u' {
+				i += 5 // \uXXXX
+			} else {
+				i++
+			}
+		}
+		size++
+	}
+	if b64 {
+		return base64.RawURLEncoding.DecodedLen(size)
+	}
+	return size
+}
+
+// decodeJSONPayload decodes the Payload found in the JSON serialization
+// formats. A missing Payload is reported as nil, as it may be detached.
+func decodeJSONPayload(raw json.RawMessage, b64 bool) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if b64 {
+		var payload buffer.Buffer
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return nil, errors.Wrap(err, `failed to decode Payload`)
+		}
+		return payload.Bytes(), nil
+	}
+	var payload string
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, errors.Wrap(err, `failed to decode Payload`)
+	}
+	return []byte(payload), nil
+}
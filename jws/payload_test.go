@@ -0,0 +1,139 @@
+package jws_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jws"
+)
+
+// Examples taken from https://tools.ietf.org/html/rfc7797#section-4
+const (
+	unencodedPayload  = `$.02`
+	unencodedHMACKey  = `AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow`
+	unencodedHeaders  = `{"alg":"HS256","b64":false,"crit":["b64"]}`
+	unencodedDetached = `eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY`
+	encodedCompact    = `eyJhbGciOiJIUzI1NiJ9.JC4wMg.5mvfOroL-g7HyqJoozehmsaqmvTYGEq5jTI1gVvoEoQ`
+)
+
+// mapHeaders implements jws.Headers without the type checks of
+// jws.StandardHeaders
+type mapHeaders map[string]interface{}
+
+func (h mapHeaders) Get(name string) (interface{}, bool) {
+	v, ok := h[name]
+	return v, ok
+}
+
+func (h mapHeaders) Set(name string, value interface{}) error {
+	h[name] = value
+	return nil
+}
+
+func (h mapHeaders) GetAlgorithm() jwa.SignatureAlgorithm {
+	alg, _ := h[jws.AlgorithmKey].(jwa.SignatureAlgorithm)
+	return alg
+}
+
+func TestUnencodedPayload(t *testing.T) {
+
+	key, err := base64.RawURLEncoding.DecodeString(unencodedHMACKey)
+	if err != nil {
+		t.Fatalf("Failed to decode HMAC Key: %s", err.Error())
+	}
+
+	t.Run("Encoded reference", func(t *testing.T) {
+		payload, err := jws.Verify([]byte(encodedCompact), jwa.HS256, key)
+		if err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+		if string(payload) != unencodedPayload {
+			t.Fatal("Mismatched payload")
+		}
+	})
+	t.Run("Detached compact", func(t *testing.T) {
+		signed, err := jws.SignDetached([]byte(unencodedPayload), jwa.HS256, key, []byte(unencodedHeaders))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if string(signed) != unencodedDetached {
+			t.Fatalf("Mismatched compact serialization: %s", signed)
+		}
+		if err := jws.VerifyDetached(signed, []byte(unencodedPayload), jwa.HS256, key); err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+	})
+	t.Run("Attached compact", func(t *testing.T) {
+		_, err := jws.SignLiteral([]byte(unencodedPayload), jwa.HS256, key, []byte(unencodedHeaders))
+		if err == nil {
+			t.Fatal("Signing an unencoded payload containing '.' should fail")
+		}
+
+		payload := []byte("no periods here")
+		signed, err := jws.SignLiteral(payload, jwa.HS256, key, []byte(unencodedHeaders))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		verified, err := jws.Verify(signed, jwa.HS256, key)
+		if err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+		if bytes.Compare(payload, verified) != 0 {
+			t.Fatalf("Mismatched payload (%s):(%s)", payload, verified)
+		}
+		msg, err := jws.ParseByte(signed)
+		if err != nil {
+			t.Fatalf("Failed to parse message: %s", err.Error())
+		}
+		if bytes.Compare(payload, msg.GetPayload()) != 0 {
+			t.Fatalf("Mismatched payload (%s):(%s)", payload, msg.GetPayload())
+		}
+	})
+	t.Run("JSON", func(t *testing.T) {
+		var protected jws.StandardHeaders
+		if err := protected.Set(jws.Base64Key, false); err != nil {
+			t.Fatalf("Failed to set b64: %s", err.Error())
+		}
+		if err := protected.Set(jws.CriticalKey, []string{jws.Base64Key}); err != nil {
+			t.Fatalf("Failed to set crit: %s", err.Error())
+		}
+		signed, err := jws.SignFlattened([]byte(unencodedPayload), jwa.HS256, key, &protected, nil)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if !bytes.Contains(signed, []byte(`"payload":"$.02"`)) {
+			t.Fatalf("Payload should not be encoded: %s", signed)
+		}
+		verified, err := jws.Verify(signed, jwa.HS256, key)
+		if err != nil {
+			t.Fatalf("Failed to verify message: %s", err.Error())
+		}
+		if string(verified) != unencodedPayload {
+			t.Fatal("Mismatched payload")
+		}
+	})
+	t.Run("Missing crit", func(t *testing.T) {
+		_, err := jws.SignDetached([]byte(unencodedPayload), jwa.HS256, key, []byte(`{"alg":"HS256","b64":false}`))
+		if err == nil {
+			t.Fatal("Signing with b64 not listed as critical should fail")
+		}
+		const token = `eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2V9..A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY`
+		if err := jws.VerifyDetached([]byte(token), []byte(unencodedPayload), jwa.HS256, key); err == nil {
+			t.Fatal("Verification with b64 not listed as critical should fail")
+		}
+	})
+	t.Run("Invalid crit", func(t *testing.T) {
+		protected := mapHeaders{jws.Base64Key: false, jws.CriticalKey: jws.Base64Key}
+		if _, err := jws.SignFlattened([]byte(unencodedPayload), jwa.HS256, key, protected, nil); err == nil {
+			t.Fatal("Signing with a crit value that is not a list should fail")
+		}
+	})
+	t.Run("Unprotected b64", func(t *testing.T) {
+		src := `{"payload":"$.02","protected":"eyJhbGciOiJIUzI1NiJ9","header":{"b64":false},"signature":"c2ln"}`
+		if _, err := jws.ParseJSON([]byte(src)); err == nil {
+			t.Fatal("Parsing an unprotected b64 header should fail")
+		}
+	})
+}
@@ -1,83 +1,297 @@
 package sign
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/hmac"
 	"crypto/rand"
+	"encoding/asn1"
+	"hash"
+	"math/big"
 
 	"github.com/pkg/errors"
 	"github.com/repenno/jwx-opa/jwa"
 )
 
-var ecdsaSignFuncs = map[jwa.SignatureAlgorithm]ecdsaSignFunc{}
+var ecdsaHashes = map[jwa.SignatureAlgorithm]crypto.Hash{
+	jwa.ES256:  crypto.SHA256,
+	jwa.ES256K: crypto.SHA256,
+	jwa.ES384:  crypto.SHA384,
+	jwa.ES512:  crypto.SHA512,
+	jwa.ESB256: crypto.SHA256,
+	jwa.ESB384: crypto.SHA384,
+	jwa.ESB512: crypto.SHA512,
+}
 
-func init() {
-	algs := map[jwa.SignatureAlgorithm]crypto.Hash{
-		jwa.ES256: crypto.SHA256,
-		jwa.ES384: crypto.SHA384,
-		jwa.ES512: crypto.SHA512,
-	}
+// ecdsaCurves maps the algorithms to the name of the only curve they may
+// be used with, so that a signature cannot be passed off as one of another
+// algorithm using the same hash function
+var ecdsaCurves = map[jwa.SignatureAlgorithm]string{
+	jwa.ES256:  "P-256",
+	jwa.ES256K: "secp256k1",
+	jwa.ES384:  "P-384",
+	jwa.ES512:  "P-521",
+	jwa.ESB256: "brainpoolP256r1",
+	jwa.ESB384: "brainpoolP384r1",
+	jwa.ESB512: "brainpoolP512r1",
+}
 
-	for alg, h := range algs {
-		ecdsaSignFuncs[alg] = makeECDSASignFunc(h)
-	}
+// ecdsaSignature is the ASN.1 structure of the signatures created by
+// crypto.Signer implementations
+type ecdsaSignature struct {
+	R, S *big.Int
 }
 
-func makeECDSASignFunc(hash crypto.Hash) ecdsaSignFunc {
-	return ecdsaSignFunc(func(payload []byte, key *ecdsa.PrivateKey) ([]byte, error) {
-		curveBits := key.Curve.Params().BitSize
-		keyBytes := curveBits / 8
-		// Curve bits do not need to be a multiple of 8.
-		if curveBits%8 > 0 {
-			keyBytes += 1
+func signECDSA(digest []byte, key crypto.Signer, hash crypto.Hash) ([]byte, error) {
+	keyBytes := ecdsaKeyBytes(key.Public().(*ecdsa.PublicKey))
+
+	var r, s *big.Int
+	if privateKey, ok := key.(*ecdsa.PrivateKey); ok {
+		var err error
+		r, s, err = ecdsa.Sign(rand.Reader, privateKey, digest)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to sign payload using ecdsa")
 		}
-		h := hash.New()
-		h.Write(payload)
-		r, s, err := ecdsa.Sign(rand.Reader, key, h.Sum(nil))
+	} else {
+		// Other signers return the ASN.1 encoding of r and s, which JWS
+		// replaces with their fixed-width concatenation
+		der, err := key.Sign(rand.Reader, digest, hash)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to sign payload using ecdsa")
 		}
+		var sig ecdsaSignature
+		if rest, err := asn1.Unmarshal(der, &sig); err != nil || len(rest) > 0 {
+			return nil, errors.New("failed to parse ASN.1 ecdsa signature")
+		}
+		if sig.R == nil || sig.S == nil || sig.R.Sign() <= 0 || sig.S.Sign() <= 0 ||
+			len(sig.R.Bytes()) > keyBytes || len(sig.S.Bytes()) > keyBytes {
+			return nil, errors.New("invalid ecdsa signature")
+		}
+		r, s = sig.R, sig.S
+	}
+	return ecdsaSignatureBytes(r, s, keyBytes), nil
+}
 
-		rBytes := r.Bytes()
-		rBytesPadded := make([]byte, keyBytes)
-		copy(rBytesPadded[keyBytes-len(rBytes):], rBytes)
+// signECDSADeterministic signs using the nonce derived from the private
+// key and the digest, as described in https://tools.ietf.org/html/rfc6979.
+// It requires access to the private key. The arithmetic on the nonce and
+// the key uses math/big, which is not constant time: the inversion of the
+// nonce is blinded, but the computation may still leak timing information.
+func signECDSADeterministic(digest []byte, key crypto.Signer, hash crypto.Hash) ([]byte, error) {
+	privateKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf(`invalid key type %T. deterministic ecdsa requires *ecdsa.PrivateKey`, key)
+	}
+	if !hash.Available() {
+		return nil, errors.Errorf(`hash function %v is not available`, hash)
+	}
 
-		sBytes := s.Bytes()
-		sBytesPadded := make([]byte, keyBytes)
-		copy(sBytesPadded[keyBytes-len(sBytes):], sBytes)
+	n := privateKey.Curve.Params().N
+	qlen := n.BitLen()
+	rlen := (qlen + 7) / 8
+	e := bits2int(digest, qlen)
+	z := new(big.Int).Mod(e, n)
 
-		out := append(rBytesPadded, sBytesPadded...)
-		return out, nil
-	})
+	// https://tools.ietf.org/html/rfc6979#section-3.2
+	mac := func(key []byte, data ...[]byte) []byte {
+		h := hmac.New(hash.New, key)
+		for _, d := range data {
+			h.Write(d)
+		}
+		return h.Sum(nil)
+	}
+	x := int2octets(privateKey.D, rlen)
+	h1 := int2octets(z, rlen)
+	v := bytes.Repeat([]byte{0x01}, hash.Size())
+	k := make([]byte, hash.Size())
+	k = mac(k, v, []byte{0x00}, x, h1)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, x, h1)
+	v = mac(k, v)
+
+	for {
+		var t []byte
+		for len(t)*8 < qlen {
+			v = mac(k, v)
+			t = append(t, v...)
+		}
+		nonce := bits2int(t, qlen)
+		if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+			x1, _ := privateKey.Curve.ScalarBaseMult(nonce.Bytes())
+			r := x1.Mod(x1, n)
+			if r.Sign() > 0 {
+				nonceInv, err := invertNonce(nonce, n)
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to sign payload using ecdsa")
+				}
+				s := new(big.Int).Mul(r, privateKey.D)
+				s.Add(s, e)
+				s.Mul(s, nonceInv)
+				s.Mod(s, n)
+				if s.Sign() > 0 {
+					return ecdsaSignatureBytes(r, s, ecdsaKeyBytes(&privateKey.PublicKey)), nil
+				}
+			}
+		}
+		k = mac(k, v, []byte{0x00})
+		v = mac(k, v)
+	}
+}
+
+// invertNonce returns the inverse of the nonce `k` modulo the prime `n`,
+// computed by Fermat's little theorem as k^(n-2) rather than with the
+// extended Euclidean algorithm, whose running time depends on its input.
+// The nonce is multiplied by a random factor beforehand, which is then
+// multiplied back into the result, so that the exponentiation does not
+// operate on the nonce itself. The result does not depend on the factor.
+func invertNonce(k, n *big.Int) (*big.Int, error) {
+	one := big.NewInt(1)
+	blind, err := rand.Int(rand.Reader, new(big.Int).Sub(n, one))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate blinding factor")
+	}
+	blind.Add(blind, one)
+
+	blinded := new(big.Int).Mul(k, blind)
+	blinded.Mod(blinded, n)
+	inv := blinded.Exp(blinded, new(big.Int).Sub(n, big.NewInt(2)), n)
+	inv.Mul(inv, blind)
+	return inv.Mod(inv, n), nil
+}
+
+// bits2int converts the leftmost `qlen` bits of `b` to an integer
+func bits2int(b []byte, qlen int) *big.Int {
+	v := new(big.Int).SetBytes(b)
+	if excess := len(b)*8 - qlen; excess > 0 {
+		v.Rsh(v, uint(excess))
+	}
+	return v
+}
+
+// int2octets converts `v` to a big-endian sequence of `rlen` bytes
+func int2octets(v *big.Int, rlen int) []byte {
+	out := make([]byte, rlen)
+	b := v.Bytes()
+	copy(out[rlen-len(b):], b)
+	return out
+}
+
+// ecdsaKeyBytes returns the size of each of r and s for the curve of `key`
+func ecdsaKeyBytes(key *ecdsa.PublicKey) int {
+	curveBits := key.Curve.Params().BitSize
+	keyBytes := curveBits / 8
+	// Curve bits do not need to be a multiple of 8.
+	if curveBits%8 > 0 {
+		keyBytes += 1
+	}
+	return keyBytes
+}
+
+// ecdsaSignatureBytes returns the fixed-width concatenation of r and s
+// used by JWS
+func ecdsaSignatureBytes(r, s *big.Int, keyBytes int) []byte {
+	rBytes := r.Bytes()
+	rBytesPadded := make([]byte, keyBytes)
+	copy(rBytesPadded[keyBytes-len(rBytes):], rBytes)
+
+	sBytes := s.Bytes()
+	sBytesPadded := make([]byte, keyBytes)
+	copy(sBytesPadded[keyBytes-len(sBytes):], sBytes)
+
+	return append(rBytesPadded, sBytesPadded...)
 }
 
 func newECDSA(alg jwa.SignatureAlgorithm) (*ECDSASigner, error) {
-	signfn, ok := ecdsaSignFuncs[alg]
+	h, ok := ecdsaHashes[alg]
 	if !ok {
 		return nil, errors.Errorf(`unsupported algorithm while trying to create ECDSA signer: %s`, alg)
 	}
 
 	return &ECDSASigner{
-		alg:  alg,
-		sign: signfn,
+		alg:   alg,
+		hash:  h,
+		curve: ecdsaCurves[alg],
+		sign:  signECDSA,
 	}, nil
 }
 
+// NewDeterministicECDSA creates the signer of the ECDSA algorithm `alg`
+// whose signatures are reproducible, as they use the deterministic nonce
+// generation described in https://tools.ietf.org/html/rfc6979. The
+// signatures are verified like any other ECDSA signature. The signer
+// requires an *ecdsa.PrivateKey, rather than any crypto.Signer.
+//
+// Unlike crypto/ecdsa, the signer computes on the secret nonce and key
+// with math/big, which is not constant time. Although the inversion of
+// the nonce is blinded, an attacker able to time signing operations
+// precisely may learn about the key, so the randomized signer created by
+// New is to be preferred unless reproducible signatures are required.
+func NewDeterministicECDSA(alg jwa.SignatureAlgorithm) (*ECDSASigner, error) {
+	s, err := newECDSA(alg)
+	if err != nil {
+		return nil, err
+	}
+	s.sign = signECDSADeterministic
+	return s, nil
+}
+
 // Algorithm returns the signer algorithm
 func (s ECDSASigner) Algorithm() jwa.SignatureAlgorithm {
 	return s.alg
 }
 
-// Sign signs payload with a ECDSA private key
+// Sign signs payload with a ECDSA private key, or a `crypto.Signer` with
+// an ECDSA public key, such as a key held by an HSM or a KMS
 func (s ECDSASigner) Sign(payload []byte, key interface{}) ([]byte, error) {
+	h, err := s.NewHash(key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(payload)
+	return s.SignHash(h, key)
+}
+
+// NewHash creates the hash.Hash the payload is written to before calling SignHash
+func (s ECDSASigner) NewHash(key interface{}) (hash.Hash, error) {
+	if _, err := s.privateKey(key); err != nil {
+		return nil, err
+	}
+	return s.hash.New(), nil
+}
+
+// SignHash signs the payload written to `h` with a ECDSA private key
+func (s ECDSASigner) SignHash(h hash.Hash, key interface{}) ([]byte, error) {
+	privateKey, err := s.privateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.sign(h.Sum(nil), privateKey, s.hash)
+}
+
+// privateKey returns `key` as a crypto.Signer, provided that its curve is
+// the one of the algorithm
+func (s ECDSASigner) privateKey(key interface{}) (crypto.Signer, error) {
+	privateKey, err := ecdsaPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if name := privateKey.Public().(*ecdsa.PublicKey).Curve.Params().Name; name != s.curve {
+		return nil, errors.Errorf(`curve %s cannot be used with algorithm %s`, name, s.alg)
+	}
+	return privateKey, nil
+}
+
+func ecdsaPrivateKey(key interface{}) (crypto.Signer, error) {
 	if key == nil {
 		return nil, errors.New(`missing private key while signing payload`)
 	}
-
-	privateKey, ok := key.(*ecdsa.PrivateKey)
+	privateKey, ok := key.(crypto.Signer)
 	if !ok {
 		return nil, errors.Errorf(`invalid key type %T. *ecdsa.PrivateKey is required`, key)
 	}
-
-	return s.sign(payload, privateKey)
+	if _, ok := privateKey.Public().(*ecdsa.PublicKey); !ok {
+		return nil, errors.Errorf(`invalid public key type %T. *ecdsa.PublicKey is required`, privateKey.Public())
+	}
+	return privateKey, nil
 }
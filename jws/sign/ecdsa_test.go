@@ -1,8 +1,18 @@
 package sign
 
 import (
-	"github.com/repenno/jwx-opa/jwa"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
 	"testing"
+
+	"github.com/repenno/jwx-opa/curves"
+	"github.com/repenno/jwx-opa/jwa"
 )
 
 func TestECDSASign(t *testing.T) {
@@ -31,4 +41,144 @@ func TestECDSASign(t *testing.T) {
 			t.Fatal("HMAC Object creation should fail")
 		}
 	})
+	t.Run("Curve binding", func(t *testing.T) {
+		keys := map[jwa.SignatureAlgorithm]elliptic.Curve{
+			jwa.ES256:  elliptic.P256(),
+			jwa.ES256K: curves.Secp256k1(),
+			jwa.ES384:  elliptic.P384(),
+			jwa.ES512:  elliptic.P521(),
+			jwa.ESB256: curves.BrainpoolP256r1(),
+			jwa.ESB384: curves.BrainpoolP384r1(),
+			jwa.ESB512: curves.BrainpoolP512r1(),
+		}
+		for keyAlg, curve := range keys {
+			key, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %s", err.Error())
+			}
+			for alg := range keys {
+				signer, err := newECDSA(alg)
+				if err != nil {
+					t.Fatalf("Signer creation failure: %s", err.Error())
+				}
+				_, err = signer.Sign([]byte("payload"), key)
+				if alg == keyAlg && err != nil {
+					t.Fatalf("%s signing failed: %s", alg, err.Error())
+				}
+				if alg != keyAlg && err == nil {
+					t.Fatalf("%s signing with a %s key should fail", alg, curve.Params().Name)
+				}
+			}
+		}
+	})
+}
+
+// TestDeterministicECDSA uses test vectors of
+// https://tools.ietf.org/html/rfc6979#appendix-A.2 with SHA-256
+func TestDeterministicECDSA(t *testing.T) {
+	tests := []struct {
+		name    string
+		curve   elliptic.Curve
+		d, x, y string
+		message string
+		r, s    string
+	}{
+		{
+			name:    "P-256 sample",
+			curve:   elliptic.P256(),
+			d:       "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721",
+			x:       "60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6",
+			y:       "7903FE1008B8BC99A41AE9E95628BC64F2F1B20C2D7E9F5177A3C294D4462299",
+			message: "sample",
+			r:       "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			s:       "F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8",
+		},
+		{
+			name:    "P-256 test",
+			curve:   elliptic.P256(),
+			d:       "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721",
+			x:       "60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6",
+			y:       "7903FE1008B8BC99A41AE9E95628BC64F2F1B20C2D7E9F5177A3C294D4462299",
+			message: "test",
+			r:       "F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+			s:       "019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083",
+		},
+		{
+			name:    "P-384 sample",
+			curve:   elliptic.P384(),
+			d:       "6B9D3DAD2E1B8C1C05B19875B6659F4DE23C3B667BF297BA9AA47740787137D896D5724E4C70A825F872C9EA60D2EDF5",
+			x:       "EC3A4E415B4E19A4568618029F427FA5DA9A8BC4AE92E02E06AAE5286B300C64DEF8F0EA9055866064A254515480BC13",
+			y:       "8015D9B72D7D57244EA8EF9AC0C621896708A59367F9DFB9F54CA84B3F1C9DB1288B231C3AE0D4FE7344FD2533264720",
+			message: "sample",
+			r:       "21B13D1E013C7FA1392D03C5F99AF8B30C570C6F98D4EA8E354B63A21D3DAA33BDE1E888E63355D92FA2B3C36D8FB2CD",
+			s:       "F3AA443FB107745BF4BD77CB3891674632068A10CA67E3D45DB2266FA7D1FEEBEFDC63ECCD1AC42EC0CB8668A4FA0AB0",
+		},
+		{
+			name:    "P-521 test",
+			curve:   elliptic.P521(),
+			d:       "0FAD06DAA62BA3B25D2FB40133DA757205DE67F5BB0018FEE8C86E1B68C7E75CAA896EB32F1F47C70855836A6D16FCC1466F6D8FBEC67DB89EC0C08B0E996B83538",
+			x:       "1894550D0785932E00EAA23B694F213F8C3121F86DC97A04E5A7167DB4E5BCD371123D46E45DB6B5D5370A7F20FB633155D38FFA16D2BD761DCAC474B9A2F5023A4",
+			y:       "0493101C962CD4D2FDDF782285E64584139C2F91B47F87FF82354D6630F746A28A0DB25741B5B34A828008B22ACC23F924FAAFBD4D33F81EA66956DFEAA2BFDFCF5",
+			message: "test",
+			r:       "00E871C4A14F993C6C7369501900C4BC1E9C7B0B4BA44E04868B30B41D8071042EB28C4C250411D0CE08CD197E4188EA4876F279F90B3D8D74A3C76E6F1E4656AA8",
+			s:       "00CD52DBAA33B063C3A6CD8058A1FB0A46A4754B034FCC644766CA14DA8CA5CA9FDE00E88C1AD60CCBA759025299079D7A427EC3CC5B619BFBC828E7769BCD694E86",
+		},
+	}
+	fromHex := func(s string) *big.Int {
+		v, _ := new(big.Int).SetString(s, 16)
+		return v
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			key := &ecdsa.PrivateKey{
+				PublicKey: ecdsa.PublicKey{Curve: test.curve, X: fromHex(test.x), Y: fromHex(test.y)},
+				D:         fromHex(test.d),
+			}
+			digest := sha256.Sum256([]byte(test.message))
+			signature, err := signECDSADeterministic(digest[:], key, crypto.SHA256)
+			if err != nil {
+				t.Fatalf("Failed to sign: %s", err.Error())
+			}
+			if expected := padHex(test.r, len(signature)) + padHex(test.s, len(signature)); hex.EncodeToString(signature) != expected {
+				t.Fatalf("Mismatched signature:\n got: %x\nwant: %s", signature, expected)
+			}
+		})
+	}
+	t.Run("ES256", func(t *testing.T) {
+		signer, err := NewDeterministicECDSA(jwa.ES256)
+		if err != nil {
+			t.Fatalf("Signer creation failure: %s", err.Error())
+		}
+		key := &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: fromHex(tests[0].x), Y: fromHex(tests[0].y)},
+			D:         fromHex(tests[0].d),
+		}
+		signature, err := signer.Sign([]byte("sample"), key)
+		if err != nil {
+			t.Fatalf("Failed to sign: %s", err.Error())
+		}
+		if expected := padHex(tests[0].r, len(signature)) + padHex(tests[0].s, len(signature)); hex.EncodeToString(signature) != expected {
+			t.Fatalf("Mismatched signature:\n got: %x\nwant: %s", signature, expected)
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		if _, err := NewDeterministicECDSA(jwa.RS256); err == nil {
+			t.Fatal("Signer creation should fail")
+		}
+		if _, err := signECDSADeterministic(nil, opaqueSigner{}, crypto.SHA256); err == nil {
+			t.Fatal("Signing with an opaque signer should fail")
+		}
+	})
+}
+
+// opaqueSigner is a crypto.Signer that does not expose its private key
+type opaqueSigner struct {
+	crypto.Signer
+}
+
+// padHex pads the hexadecimal integer `v` to the size of r or s in a
+// signature of `signatureSize` bytes
+func padHex(v string, signatureSize int) string {
+	return strings.Repeat("0", signatureSize-len(v)) + strings.ToLower(v)
 }
@@ -0,0 +1,40 @@
+package sign
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+)
+
+func newEdDSA() (*EdDSASigner, error) {
+	return &EdDSASigner{}, nil
+}
+
+// Algorithm returns the signer algorithm
+func (s EdDSASigner) Algorithm() jwa.SignatureAlgorithm {
+	return jwa.EdDSA
+}
+
+// Sign signs payload with an Ed25519 private key, or a `crypto.Signer`
+// with an Ed25519 public key. EdDSA signs the payload itself rather than
+// its digest, see https://tools.ietf.org/html/rfc8037#section-3.1
+func (s EdDSASigner) Sign(payload []byte, key interface{}) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New(`missing private key while signing payload`)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf(`invalid key type %T. ed25519.PrivateKey is required`, key)
+	}
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return nil, errors.Errorf(`invalid public key type %T. ed25519.PublicKey is required`, signer.Public())
+	}
+	signature, err := signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign payload using eddsa")
+	}
+	return signature, nil
+}
@@ -10,41 +10,24 @@ import (
 	"github.com/repenno/jwx-opa/jwa"
 )
 
-var hmacSignFuncs = map[jwa.SignatureAlgorithm]hmacSignFunc{}
-
-func init() {
-	algs := map[jwa.SignatureAlgorithm]func() hash.Hash{
-		jwa.HS256: sha256.New,
-		jwa.HS384: sha512.New384,
-		jwa.HS512: sha512.New,
-	}
-
-	for alg, h := range algs {
-		hmacSignFuncs[alg] = makeHMACSignFunc(h)
-
-	}
+var hmacHashFuncs = map[jwa.SignatureAlgorithm]func() hash.Hash{
+	jwa.HS256: sha256.New,
+	jwa.HS384: sha512.New384,
+	jwa.HS512: sha512.New,
 }
 
 func newHMAC(alg jwa.SignatureAlgorithm) (*HMACSigner, error) {
-	signer, ok := hmacSignFuncs[alg]
+	hfunc, ok := hmacHashFuncs[alg]
 	if !ok {
 		return nil, errors.Errorf(`unsupported algorithm while trying to create HMAC signer: %s`, alg)
 	}
 
 	return &HMACSigner{
 		alg:  alg,
-		sign: signer,
+		hash: hfunc,
 	}, nil
 }
 
-func makeHMACSignFunc(hfunc func() hash.Hash) hmacSignFunc {
-	return hmacSignFunc(func(payload []byte, key []byte) ([]byte, error) {
-		h := hmac.New(hfunc, key)
-		h.Write(payload)
-		return h.Sum(nil), nil
-	})
-}
-
 // Algorithm returns the signer algorithm
 func (s HMACSigner) Algorithm() jwa.SignatureAlgorithm {
 	return s.alg
@@ -52,6 +35,16 @@ func (s HMACSigner) Algorithm() jwa.SignatureAlgorithm {
 
 // Sign signs payload with a Symmetric  key
 func (s HMACSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
+	h, err := s.NewHash(key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(payload)
+	return s.SignHash(h, key)
+}
+
+// NewHash creates the keyed hash.Hash the payload is written to before calling SignHash
+func (s HMACSigner) NewHash(key interface{}) (hash.Hash, error) {
 	hmackey, ok := key.([]byte)
 	if !ok {
 		return nil, errors.Errorf(`invalid key type %T. []byte is required`, key)
@@ -61,5 +54,10 @@ func (s HMACSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
 		return nil, errors.New(`missing key while signing payload`)
 	}
 
-	return s.sign(payload, hmackey)
+	return hmac.New(s.hash, hmackey), nil
+}
+
+// SignHash returns the MAC of the payload written to `h`
+func (s HMACSigner) SignHash(h hash.Hash, key interface{}) ([]byte, error) {
+	return h.Sum(nil), nil
 }
@@ -1,8 +1,8 @@
 package sign
 
 import (
-	"crypto/ecdsa"
-	"crypto/rsa"
+	"crypto"
+	"hash"
 
 	"github.com/repenno/jwx-opa/jwa"
 )
@@ -20,26 +20,54 @@ type Signer interface {
 	Algorithm() jwa.SignatureAlgorithm
 }
 
-type rsaSignFunc func([]byte, *rsa.PrivateKey) ([]byte, error)
+// HashSigner is implemented by signers that can sign a payload
+// incrementally, by writing it to a hash.Hash instead of holding
+// it in memory
+type HashSigner interface {
+	Signer
+
+	// NewHash creates the hash.Hash that the payload to be signed
+	// with `key` is written to
+	NewHash(key interface{}) (hash.Hash, error)
+	// SignHash creates a signature for the payload written to `h`,
+	// which must have been created by NewHash for the same `key`
+	SignHash(h hash.Hash, key interface{}) ([]byte, error)
+}
+
+// rsaSignFunc signs the digest of a payload
+type rsaSignFunc func([]byte, crypto.Signer) ([]byte, error)
 
 // RSASigner uses crypto/rsa to sign the payloads.
 type RSASigner struct {
 	alg  jwa.SignatureAlgorithm
+	hash crypto.Hash
 	sign rsaSignFunc
 }
 
-type ecdsaSignFunc func([]byte, *ecdsa.PrivateKey) ([]byte, error)
+// ecdsaSignFunc signs the digest of a payload computed with the given hash
+type ecdsaSignFunc func([]byte, crypto.Signer, crypto.Hash) ([]byte, error)
 
 // ECDSASigner uses crypto/ecdsa to sign the payloads.
 type ECDSASigner struct {
-	alg  jwa.SignatureAlgorithm
-	sign ecdsaSignFunc
+	alg   jwa.SignatureAlgorithm
+	hash  crypto.Hash
+	curve string // Name of the curve the algorithm is bound to
+	sign  ecdsaSignFunc
 }
 
-type hmacSignFunc func([]byte, []byte) ([]byte, error)
-
 // HMACSigner uses crypto/hmac to sign the payloads.
 type HMACSigner struct {
 	alg  jwa.SignatureAlgorithm
-	sign hmacSignFunc
+	hash func() hash.Hash
+}
+
+// NoneSigner creates the empty signature of unsecured JWS messages.
+type NoneSigner struct{}
+
+// EdDSASigner uses crypto/ed25519 to sign the payloads.
+type EdDSASigner struct{}
+
+// MLDSASigner uses the mldsa package to sign the payloads.
+type MLDSASigner struct {
+	alg jwa.SignatureAlgorithm
 }
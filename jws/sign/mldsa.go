@@ -0,0 +1,44 @@
+package sign
+
+import (
+	"crypto"
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/mldsa"
+)
+
+func newMLDSA(alg jwa.SignatureAlgorithm) (*MLDSASigner, error) {
+	return &MLDSASigner{alg: alg}, nil
+}
+
+// Algorithm returns the signer algorithm
+func (s MLDSASigner) Algorithm() jwa.SignatureAlgorithm {
+	return s.alg
+}
+
+// Sign signs payload with an ML-DSA private key, or a `crypto.Signer`
+// with an ML-DSA public key, whose parameter set must match the algorithm.
+// The payload itself is signed, with an empty context string.
+func (s MLDSASigner) Sign(payload []byte, key interface{}) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New(`missing private key while signing payload`)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf(`invalid key type %T. *mldsa.PrivateKey is required`, key)
+	}
+	publicKey, ok := signer.Public().(*mldsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf(`invalid public key type %T. *mldsa.PublicKey is required`, signer.Public())
+	}
+	if name := publicKey.Parameters().Name(); name != string(s.alg) {
+		return nil, errors.Errorf(`%s key cannot be used with algorithm %s`, name, s.alg)
+	}
+	signature, err := signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign payload using ml-dsa")
+	}
+	return signature, nil
+}
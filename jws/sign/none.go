@@ -0,0 +1,34 @@
+package sign
+
+import (
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+)
+
+// noneKey is the type of UnsafeAllowNone, which no other value can be
+// mistaken for
+type noneKey string
+
+// UnsafeAllowNone is the key that must be passed to sign and verify
+// unsecured JWS messages using the "none" algorithm. Any other key is
+// rejected, so that the algorithm is never used without an explicit
+// opt-in. See https://tools.ietf.org/html/rfc7518#section-3.6
+const UnsafeAllowNone noneKey = "none algorithm is allowed"
+
+func newNone() (*NoneSigner, error) {
+	return &NoneSigner{}, nil
+}
+
+// Algorithm returns the signer algorithm
+func (s NoneSigner) Algorithm() jwa.SignatureAlgorithm {
+	return jwa.NoSignature
+}
+
+// Sign returns the empty signature of an unsecured JWS message. `key`
+// must be UnsafeAllowNone.
+func (s NoneSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
+	if key != UnsafeAllowNone {
+		return nil, errors.Errorf(`algorithm %s requires the UnsafeAllowNone key`, jwa.NoSignature)
+	}
+	return []byte{}, nil
+}
@@ -4,12 +4,14 @@ import (
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
+	"hash"
 
 	"github.com/pkg/errors"
 	"github.com/repenno/jwx-opa/jwa"
 )
 
 var rsaSignFuncs = map[jwa.SignatureAlgorithm]rsaSignFunc{}
+var rsaHashes = map[jwa.SignatureAlgorithm]crypto.Hash{}
 
 func init() {
 	algs := map[jwa.SignatureAlgorithm]struct {
@@ -44,23 +46,21 @@ func init() {
 
 	for alg, item := range algs {
 		rsaSignFuncs[alg] = item.SignFunc(item.Hash)
+		rsaHashes[alg] = item.Hash
 	}
 }
 
 func makeSignPKCS1v15(hash crypto.Hash) rsaSignFunc {
-	return rsaSignFunc(func(payload []byte, key *rsa.PrivateKey) ([]byte, error) {
-		h := hash.New()
-		h.Write(payload)
-		return rsa.SignPKCS1v15(rand.Reader, key, hash, h.Sum(nil))
+	return rsaSignFunc(func(digest []byte, key crypto.Signer) ([]byte, error) {
+		return key.Sign(rand.Reader, digest, hash)
 	})
 }
 
 func makeSignPSS(hash crypto.Hash) rsaSignFunc {
-	return rsaSignFunc(func(payload []byte, key *rsa.PrivateKey) ([]byte, error) {
-		h := hash.New()
-		h.Write(payload)
-		return rsa.SignPSS(rand.Reader, key, hash, h.Sum(nil), &rsa.PSSOptions{
+	return rsaSignFunc(func(digest []byte, key crypto.Signer) ([]byte, error) {
+		return key.Sign(rand.Reader, digest, &rsa.PSSOptions{
 			SaltLength: rsa.PSSSaltLengthAuto,
+			Hash:       hash,
 		})
 	})
 }
@@ -72,6 +72,7 @@ func newRSA(alg jwa.SignatureAlgorithm) (*RSASigner, error) {
 	}
 	return &RSASigner{
 		alg:  alg,
+		hash: rsaHashes[alg],
 		sign: signfn,
 	}, nil
 }
@@ -82,15 +83,44 @@ func (s RSASigner) Algorithm() jwa.SignatureAlgorithm {
 }
 
 // Sign creates a signature using crypto/rsa. key must be a non-nil instance of
-// `*"crypto/rsa".PrivateKey`.
+// `*"crypto/rsa".PrivateKey`, or a `crypto.Signer` with an RSA public key,
+// such as a key held by an HSM or a KMS.
 func (s RSASigner) Sign(payload []byte, key interface{}) ([]byte, error) {
+	h, err := s.NewHash(key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(payload)
+	return s.SignHash(h, key)
+}
+
+// NewHash creates the hash.Hash the payload is written to before calling SignHash
+func (s RSASigner) NewHash(key interface{}) (hash.Hash, error) {
+	if _, err := rsaPrivateKey(key); err != nil {
+		return nil, err
+	}
+	return s.hash.New(), nil
+}
+
+// SignHash creates a signature using crypto/rsa for the payload written to `h`
+func (s RSASigner) SignHash(h hash.Hash, key interface{}) ([]byte, error) {
+	rsakey, err := rsaPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.sign(h.Sum(nil), rsakey)
+}
+
+func rsaPrivateKey(key interface{}) (crypto.Signer, error) {
 	if key == nil {
 		return nil, errors.New(`missing private key while signing payload`)
 	}
-	rsakey, ok := key.(*rsa.PrivateKey)
+	rsakey, ok := key.(crypto.Signer)
 	if !ok {
 		return nil, errors.Errorf(`invalid key type %T. *rsa.PrivateKey is required`, key)
 	}
-
-	return s.sign(payload, rsakey)
+	if _, ok := rsakey.Public().(*rsa.PublicKey); !ok {
+		return nil, errors.Errorf(`invalid public key type %T. *rsa.PublicKey is required`, rsakey.Public())
+	}
+	return rsakey, nil
 }
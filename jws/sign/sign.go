@@ -1,19 +1,77 @@
 package sign
 
 import (
+	"sync"
+
 	"github.com/pkg/errors"
 	"github.com/repenno/jwx-opa/jwa"
 )
 
+// SignerFactory creates the Signer of an algorithm added with Register
+type SignerFactory func(alg jwa.SignatureAlgorithm) (Signer, error)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[jwa.SignatureAlgorithm]SignerFactory{}
+)
+
 // New creates a signer that signs payloads using the given signature algorithm.
+// Algorithms added with Register are supported as well.
 func New(alg jwa.SignatureAlgorithm) (Signer, error) {
+	registryMutex.RLock()
+	factory, ok := registry[alg]
+	registryMutex.RUnlock()
+	if ok {
+		return factory(alg)
+	}
+	return newBuiltin(alg)
+}
+
+// Register makes New create signers for `alg` using `factory`. The name
+// of the algorithm is to be registered with jwa.RegisterSignatureAlgorithm
+// as well, so that it is accepted in header parameters. The algorithms
+// supported by this package cannot be replaced. The factory is removed
+// with Unregister.
+func Register(alg jwa.SignatureAlgorithm, factory SignerFactory) error {
+	if factory == nil {
+		return errors.Errorf(`missing signer factory for %s`, alg)
+	}
+	if _, err := newBuiltin(alg); err == nil {
+		return errors.Errorf(`signature algorithm %s cannot be replaced`, alg)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[alg] = factory
+	return nil
+}
+
+// Unregister removes the signer factory of `alg` added with Register
+func Unregister(alg jwa.SignatureAlgorithm) error {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := registry[alg]; !ok {
+		return errors.Errorf(`signature algorithm %s is not registered`, alg)
+	}
+	delete(registry, alg)
+	return nil
+}
+
+// newBuiltin creates the signer of an algorithm supported by this package
+func newBuiltin(alg jwa.SignatureAlgorithm) (Signer, error) {
 	switch alg {
 	case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512:
 		return newRSA(alg)
-	case jwa.ES256, jwa.ES384, jwa.ES512:
+	case jwa.ES256, jwa.ES256K, jwa.ES384, jwa.ES512, jwa.ESB256, jwa.ESB384, jwa.ESB512:
 		return newECDSA(alg)
 	case jwa.HS256, jwa.HS384, jwa.HS512:
 		return newHMAC(alg)
+	case jwa.EdDSA:
+		return newEdDSA()
+	case jwa.MLDSA44, jwa.MLDSA65, jwa.MLDSA87:
+		return newMLDSA(alg)
+	case jwa.NoSignature:
+		return newNone()
 	default:
 		return nil, errors.Errorf(`unsupported signature algorithm %s`, alg)
 	}
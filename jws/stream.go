@@ -0,0 +1,222 @@
+package jws
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jws/sign"
+	"github.com/repenno/jwx-opa/jws/verify"
+)
+
+// SignReader generates a Signature for the Payload read from `payload` and
+// the given Headers, and writes the message to `w` in compact serialization
+// format. The Payload is streamed through the signer, so it is never held
+// in memory as a whole. If an error occurs, a partial message may have
+// been written to `w`. If `hdrBuf` is nil, Headers containing only the
+// algorithm are used. The WithHeaders option does not apply, as the
+// Headers are given by `hdrBuf`. Unsecured messages cannot be streamed, so
+// the "none" algorithm is rejected even with the WithAllowNone option.
+func SignReader(w io.Writer, payload io.Reader, alg jwa.SignatureAlgorithm, key interface{}, hdrBuf []byte, options ...SignOption) error {
+	if err := checkStreamAlgorithm(alg); err != nil {
+		return err
+	}
+	if hdrBuf == nil {
+		var err error
+		if hdrBuf, err = algorithmHeaders(alg); err != nil {
+			return err
+		}
+	}
+	encodedHdr, b64, err := encodeCompactHeaders(hdrBuf)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, encodedHdr+"."); err != nil {
+		return errors.Wrap(err, `failed to write Headers`)
+	}
+	out := w
+	if !b64 {
+		// https://tools.ietf.org/html/rfc7797#section-5.2
+		out = noDotWriter{w}
+	}
+	encodedSignature, err := signReader(encodedHdr, payload, b64, alg, key, out, newSignConfig(options).newSigner)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "."+encodedSignature); err != nil {
+		return errors.Wrap(err, `failed to write Signature`)
+	}
+	return nil
+}
+
+// SignDetachedReader generates a Signature for the Payload read from
+// `payload` and the given Headers, and serializes it in compact
+// serialization format with a detached Payload. It behaves like
+// SignDetached, but streams the Payload through the signer instead of
+// holding it in memory. Like SignReader, it rejects the "none" algorithm.
+func SignDetachedReader(payload io.Reader, alg jwa.SignatureAlgorithm, key interface{}, hdrBuf []byte, options ...SignOption) ([]byte, error) {
+	if err := checkStreamAlgorithm(alg); err != nil {
+		return nil, err
+	}
+	if hdrBuf == nil {
+		var err error
+		if hdrBuf, err = algorithmHeaders(alg); err != nil {
+			return nil, err
+		}
+	}
+	encodedHdr, b64, err := encodeCompactHeaders(hdrBuf)
+	if err != nil {
+		return nil, err
+	}
+	encodedSignature, err := signReader(encodedHdr, payload, b64, alg, key, nil, newSignConfig(options).newSigner)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(encodedHdr + ".." + encodedSignature), nil
+}
+
+// VerifyDetachedReader checks if the given JWS message, serialized in
+// compact serialization format with a detached Payload, is verifiable
+// using `alg` and `key` over the Payload read from `payload`. It behaves
+// like VerifyDetached, but streams the Payload through the verifier
+// instead of holding it in memory.
+func VerifyDetachedReader(buf []byte, payload io.Reader, alg jwa.SignatureAlgorithm, key interface{}, options ...VerifyOption) error {
+
+	if _, err := verify.New(alg); err != nil {
+		return errors.Wrap(err, "failed to create verifier")
+	}
+
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 {
+		return errors.New(`attempt to verify empty buffer`)
+	}
+
+	parts, err := SplitCompact(string(buf[:]))
+	if err != nil {
+		return errors.Wrap(err, `failed extract from compact serialization format`)
+	}
+	if parts[1] != "" {
+		return errors.New(`Payload is not detached`)
+	}
+	cfg := newVerifyConfig(options)
+	m, err := parseCompact(string(buf[:]), newParseConfig(cfg.parseOptions))
+	if err != nil {
+		return errors.Wrap(err, `failed to parse compact serialization format`)
+	}
+	return cfg.verifyReader(m.Signatures[0], payload, alg, key)
+}
+
+// checkStreamAlgorithm rejects the algorithms that cannot sign a streamed
+// Payload before anything is written
+func checkStreamAlgorithm(alg jwa.SignatureAlgorithm) error {
+	if alg == jwa.NoSignature {
+		return errors.Errorf(`algorithm %s cannot sign a streamed Payload`, alg)
+	}
+	return nil
+}
+
+// signReader signs the encoded Headers and the Payload read from `payload`,
+// and returns the encoded Signature computed by the signer created by
+// `newSigner`. The representation of the Payload is also written to `w`,
+// unless it is nil.
+func signReader(encodedHdr string, payload io.Reader, b64 bool, alg jwa.SignatureAlgorithm, key interface{}, w io.Writer, newSigner sign.SignerFactory) (string, error) {
+	signer, err := newSigner(alg)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to create signer`)
+	}
+	hs, ok := signer.(sign.HashSigner)
+	if !ok {
+		return "", errors.Errorf(`signer for %s cannot sign incrementally`, alg)
+	}
+	h, err := hs.NewHash(key)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to sign Payload`)
+	}
+	if err := writeSigningInput(h, encodedHdr, payload, b64, w); err != nil {
+		return "", err
+	}
+	signature, err := hs.SignHash(h, key)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to sign Payload`)
+	}
+	return base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verifyReader verifies `sig` using `alg` and `key`, streaming the Payload
+// read from `payload` through the verifier
+func (cfg *verifyConfig) verifyReader(sig *Signature, payload io.Reader, alg jwa.SignatureAlgorithm, key interface{}) error {
+
+	if err := cfg.checkCritical(sig.Protected, sig.Headers); err != nil {
+		return errors.Wrap(err, `invalid critical header parameters`)
+	}
+	if err := cfg.checkAlgorithm(sig.Protected, alg, key); err != nil {
+		return errors.Wrap(err, `invalid algorithm`)
+	}
+	verifier, err := verify.New(alg)
+	if err != nil {
+		return errors.Wrap(err, "failed to create verifier")
+	}
+	hv, ok := verifier.(verify.HashVerifier)
+	if !ok {
+		return errors.Errorf(`verifier for %s cannot verify incrementally`, alg)
+	}
+	h, err := hv.NewHash(key)
+	if err != nil {
+		return errors.Wrap(err, `failed to verify message`)
+	}
+
+	protected, err := sig.protectedBytes()
+	if err != nil {
+		return err
+	}
+	b64, err := isBase64Payload(sig.Protected)
+	if err != nil {
+		return errors.Wrap(err, `invalid Payload encoding`)
+	}
+	if err := writeSigningInput(h, base64.RawURLEncoding.EncodeToString(protected), payload, b64, nil); err != nil {
+		return err
+	}
+	return hv.VerifyHash(h, sig.Signature, key)
+}
+
+// writeSigningInput writes the JWS Signing Input to `h`, streaming the
+// Payload read from `payload`. The representation of the Payload is also
+// written to `w`, unless it is nil.
+func writeSigningInput(h io.Writer, encodedHdr string, payload io.Reader, b64 bool, w io.Writer) error {
+	if _, err := io.WriteString(h, encodedHdr+"."); err != nil {
+		return errors.Wrap(err, `failed to write Headers`)
+	}
+	dst := h
+	if w != nil {
+		dst = io.MultiWriter(h, w)
+	}
+	if !b64 {
+		if _, err := io.Copy(dst, payload); err != nil {
+			return errors.Wrap(err, `failed to read Payload`)
+		}
+		return nil
+	}
+	enc := base64.NewEncoder(base64.RawURLEncoding, dst)
+	if _, err := io.Copy(enc, payload); err != nil {
+		return errors.Wrap(err, `failed to read Payload`)
+	}
+	if err := enc.Close(); err != nil {
+		return errors.Wrap(err, `failed to encode Payload`)
+	}
+	return nil
+}
+
+// noDotWriter rejects unencoded Payloads containing '.', which cannot be
+// used in compact serialization
+type noDotWriter struct {
+	w io.Writer
+}
+
+func (w noDotWriter) Write(p []byte) (int, error) {
+	if bytes.IndexByte(p, '.') >= 0 {
+		return 0, errors.New(`unencoded Payload must not contain '.' in compact serialization`)
+	}
+	return w.w.Write(p)
+}
@@ -0,0 +1,115 @@
+package jws_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jws"
+)
+
+func TestStream(t *testing.T) {
+	payload := bytes.Repeat([]byte("Lorem ipsum dolor sit amet. "), 1<<15)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	sharedKey := []byte("Avracadabra")
+
+	tests := []struct {
+		alg       jwa.SignatureAlgorithm
+		signKey   interface{}
+		verifyKey interface{}
+	}{
+		{jwa.RS256, rsaKey, &rsaKey.PublicKey},
+		{jwa.PS384, rsaKey, &rsaKey.PublicKey},
+		{jwa.ES256, ecKey, &ecKey.PublicKey},
+		{jwa.HS512, sharedKey, sharedKey},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.alg.String(), func(t *testing.T) {
+			hdrBuf := []byte(`{"alg":"` + test.alg.String() + `"}`)
+
+			var buf bytes.Buffer
+			if err := jws.SignReader(&buf, bytes.NewReader(payload), test.alg, test.signKey, hdrBuf); err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			verified, err := jws.Verify(buf.Bytes(), test.alg, test.verifyKey)
+			if err != nil {
+				t.Fatalf("Failed to verify streamed message: %s", err.Error())
+			}
+			if !bytes.Equal(verified, payload) {
+				t.Fatal("Mismatched payload")
+			}
+
+			detached, err := jws.SignDetachedReader(bytes.NewReader(payload), test.alg, test.signKey, nil)
+			if err != nil {
+				t.Fatalf("Failed to sign payload: %s", err.Error())
+			}
+			if err := jws.VerifyDetached(detached, payload, test.alg, test.verifyKey); err != nil {
+				t.Fatalf("Failed to verify streamed detached message: %s", err.Error())
+			}
+			if err := jws.VerifyDetachedReader(detached, bytes.NewReader(payload), test.alg, test.verifyKey); err != nil {
+				t.Fatalf("Failed to verify detached message from reader: %s", err.Error())
+			}
+			if err := jws.VerifyDetachedReader(detached, bytes.NewReader(payload[1:]), test.alg, test.verifyKey); err == nil {
+				t.Fatal("Verification of a tampered payload should fail")
+			}
+		})
+	}
+
+	t.Run("Unencoded payload", func(t *testing.T) {
+		hdrBuf := []byte(`{"alg":"HS256","b64":false,"crit":["b64"]}`)
+		detached, err := jws.SignDetachedReader(bytes.NewReader(payload), jwa.HS256, sharedKey, hdrBuf)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		expected, err := jws.SignDetached(payload, jwa.HS256, sharedKey, hdrBuf)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if !bytes.Equal(detached, expected) {
+			t.Fatal("Streamed and in-memory signatures differ")
+		}
+		if err := jws.VerifyDetachedReader(detached, bytes.NewReader(payload), jwa.HS256, sharedKey); err != nil {
+			t.Fatalf("Failed to verify detached message from reader: %s", err.Error())
+		}
+		var buf bytes.Buffer
+		if err := jws.SignReader(&buf, bytes.NewReader(payload), jwa.HS256, sharedKey, hdrBuf); err == nil {
+			t.Fatal("Unencoded payload containing '.' should be rejected")
+		}
+	})
+	t.Run("Default Headers", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := jws.SignReader(&buf, bytes.NewReader(payload), jwa.HS256, sharedKey, nil); err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		expected, err := jws.SignWithOption(payload, jwa.HS256, sharedKey)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Fatal("Streamed and in-memory signatures differ")
+		}
+	})
+	t.Run("Unknown critical extension", func(t *testing.T) {
+		hdrBuf := []byte(`{"alg":"HS256","crit":["exp"],"exp":1363284000}`)
+		detached, err := jws.SignDetachedReader(bytes.NewReader(payload), jwa.HS256, sharedKey, hdrBuf)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if err := jws.VerifyDetachedReader(detached, bytes.NewReader(payload), jwa.HS256, sharedKey); err == nil {
+			t.Fatal("Verification with an unknown critical extension should fail")
+		}
+	})
+}
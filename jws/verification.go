@@ -0,0 +1,296 @@
+package jws
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jwk"
+	"github.com/repenno/jwx-opa/jws/verify"
+	"github.com/repenno/jwx-opa/mldsa"
+)
+
+// verifyCandidate is an algorithm and key pair to verify a signature with
+type verifyCandidate struct {
+	alg    jwa.SignatureAlgorithm
+	key    interface{}
+	source jwk.Key // JWK the key was materialized from, if any
+}
+
+// candidatesFunc lists the candidates to verify a signature with, or
+// reports why no key can be used for it
+type candidatesFunc func(*Signature) ([]verifyCandidate, error)
+
+// withKey verifies every signature using `alg` and `key`
+func withKey(alg jwa.SignatureAlgorithm, key interface{}) candidatesFunc {
+	return func(*Signature) ([]verifyCandidate, error) {
+		return []verifyCandidate{{alg: alg, key: key}}, nil
+	}
+}
+
+// withJWKs verifies every signature with each of the `keys`, using the
+// algorithm declared by the key. In the strict algorithm mode, keys that
+// do not declare an algorithm are used with the "alg" protected header
+// parameter, which is vetted by checkAlgorithm.
+func (cfg *verifyConfig) withJWKs(keys []jwk.Key) candidatesFunc {
+	return func(sig *Signature) ([]verifyCandidate, error) {
+		var candidates []verifyCandidate
+		for _, key := range keys {
+			alg := key.GetAlgorithm()
+			if alg == jwa.NoValue && cfg.allowedAlgorithms != nil && sig.Protected != nil {
+				alg = sig.Protected.GetAlgorithm()
+			}
+			keyVal, err := key.Materialize()
+			if err != nil {
+				continue
+			}
+			candidates = append(candidates, verifyCandidate{alg: alg, key: keyVal, source: key})
+		}
+		return candidates, nil
+	}
+}
+
+// withJWKSet verifies every signature with the keys of the set that are
+// selected for it by selectKeys
+func (cfg *verifyConfig) withJWKSet(keys []jwk.Key) candidatesFunc {
+	return func(sig *Signature) ([]verifyCandidate, error) {
+		selected := cfg.selectKeys(sig, keys)
+		if len(selected) == 0 {
+			if kid := signatureKeyID(sig); kid != "" {
+				return nil, errors.Errorf(`no usable key matches key ID %s`, kid)
+			}
+			return nil, errors.New(`message has no key ID`)
+		}
+		return cfg.withJWKs(selected)(sig)
+	}
+}
+
+// selectKeys picks the keys that are usable for verifying `sig` and are
+// accepted by the caller, among which the ones matching the "kid" header
+// parameter. All of the usable keys are only picked when the message has
+// no key ID or no key matches it, and the caller allowed the fallback.
+func (cfg *verifyConfig) selectKeys(sig *Signature, keys []jwk.Key) []jwk.Key {
+
+	kid := signatureKeyID(sig)
+	var usable, matching []jwk.Key
+	for _, key := range keys {
+		if !isVerificationKey(key, sig.Protected) {
+			continue
+		}
+		if cfg.keyAccept != nil && !cfg.keyAccept(key) {
+			continue
+		}
+		usable = append(usable, key)
+		if kid != "" && key.GetKeyID() == kid {
+			matching = append(matching, key)
+		}
+	}
+
+	if len(matching) == 0 && cfg.keyFallback {
+		return usable
+	}
+	return matching
+}
+
+// signatureKeyID returns the "kid" header parameter of `sig`
+func signatureKeyID(sig *Signature) string {
+	if v, ok := signatureHeader(sig, KeyIDKey); ok {
+		if kid, ok := v.(string); ok {
+			return kid
+		}
+	}
+	return ""
+}
+
+// signatureHeader returns the header parameter `name` of `sig`, which may
+// be given in either the protected or the unprotected Headers
+func signatureHeader(sig *Signature, name string) (interface{}, bool) {
+	for _, hdr := range []Headers{sig.Protected, sig.Headers} {
+		if hdr == nil {
+			continue
+		}
+		if v, ok := hdr.Get(name); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// isVerificationKey checks that the "use", "key_ops" and "alg" parameters
+// of `key` allow verifying a signature with the given protected Headers
+func isVerificationKey(key jwk.Key, protected Headers) bool {
+
+	if use := key.GetKeyUsage(); use != "" && use != string(jwk.ForSignature) {
+		return false
+	}
+	if ops := key.GetKeyOps(); len(ops) > 0 {
+		var ok bool
+		for _, op := range ops {
+			if op == jwk.KeyOpVerify {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if alg := key.GetAlgorithm(); alg != jwa.NoValue && protected != nil {
+		if hdrAlg := protected.GetAlgorithm(); hdrAlg != jwa.NoValue && hdrAlg != alg {
+			return false
+		}
+	}
+	return true
+}
+
+// withEmbeddedJWK verifies every signature with the key found in its
+// protected "jwk" header parameter, provided that the key is a public key
+// trusted by the caller. The key is used with the algorithm it declares,
+// or else with the "alg" protected header parameter, which must fit the
+// type of the key.
+func (cfg *verifyConfig) withEmbeddedJWK() candidatesFunc {
+	return func(sig *Signature) ([]verifyCandidate, error) {
+		if sig.Protected == nil {
+			return nil, errors.New(`missing protected Headers`)
+		}
+		v, ok := sig.Protected.Get(JWKKey)
+		if !ok {
+			return nil, errors.Errorf(`missing protected %s header parameter`, JWKKey)
+		}
+		key, ok := v.(jwk.Key)
+		if !ok {
+			return nil, errors.Errorf(`invalid value for %s key: %T`, JWKKey, v)
+		}
+		if !cfg.isTrustedKey(key) {
+			return nil, errors.New(`embedded key is not trusted`)
+		}
+		keyVal, err := key.Materialize()
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to materialize embedded key`)
+		}
+		if !isPublicKey(keyVal) {
+			return nil, errors.New(`embedded key must be a public key`)
+		}
+		alg := key.GetAlgorithm()
+		if alg == jwa.NoValue {
+			alg = sig.Protected.GetAlgorithm()
+		}
+		if err := checkKeyType(alg, keyVal); err != nil {
+			return nil, err
+		}
+		return []verifyCandidate{{alg: alg, key: keyVal, source: key}}, nil
+	}
+}
+
+// isTrustedKey checks whether the caller trusts the embedded `key`, either
+// by its JWK Thumbprint or through the trusted key function
+func (cfg *verifyConfig) isTrustedKey(key jwk.Key) bool {
+	if len(cfg.thumbprints) > 0 {
+		if thumbprint, err := jwk.Thumbprint(key, cfg.thumbprintHash); err == nil {
+			if _, ok := cfg.thumbprints[string(thumbprint)]; ok {
+				return true
+			}
+		}
+	}
+	return cfg.trustedKey != nil && cfg.trustedKey(key)
+}
+
+// checkKeyType makes sure that `key` can be used with `alg`
+func checkKeyType(alg jwa.SignatureAlgorithm, key interface{}) error {
+	if keyType := jwk.GetKeyTypeFromKey(key); keyType != alg.KeyType() {
+		return errors.Errorf(`key type %s cannot be used with algorithm %s`, keyType, alg)
+	}
+	return nil
+}
+
+// isPublicKey reports whether `key` is an asymmetric public key
+func isPublicKey(key interface{}) bool {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, *mldsa.PublicKey:
+		return true
+	default:
+		return false
+	}
+}
+
+// verifyMessage verifies the signatures of `m` in turn, and returns the
+// message made of the first one that matches any of its candidates. The
+// unprotected Headers of the signature are left out, as the signature
+// does not cover them.
+func (cfg *verifyConfig) verifyMessage(m *Message, candidates candidatesFunc) (*Message, error) {
+
+	err := errors.New(`message has no signatures`)
+	for _, sig := range m.Signatures {
+		var candidate *verifyCandidate
+		if candidate, err = cfg.verifySignature(m.Payload, sig, candidates); err == nil {
+			return &Message{
+				Payload: m.Payload,
+				Signatures: []*Signature{{
+					Protected:    sig.Protected,
+					Signature:    sig.Signature,
+					rawProtected: sig.rawProtected,
+				}},
+				verified: candidate,
+			}, nil
+		}
+	}
+	return nil, errors.Wrap(err, `failed to verify message`)
+}
+
+// verifySignature returns the candidate that verifies `sig`
+func (cfg *verifyConfig) verifySignature(payload []byte, sig *Signature, candidatesFor candidatesFunc) (*verifyCandidate, error) {
+
+	if err := cfg.checkCritical(sig.Protected, sig.Headers); err != nil {
+		return nil, errors.Wrap(err, `invalid critical header parameters`)
+	}
+	signingInput, err := sig.signingInput(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to compute signing input`)
+	}
+
+	candidates, err := candidatesFor(sig)
+	if err != nil {
+		return nil, err
+	}
+	err = errors.New(`no keys to verify with`)
+	for i := range candidates {
+		if err = cfg.verifyWith(sig, signingInput, candidates[i]); err == nil {
+			return &candidates[i], nil
+		}
+	}
+	return nil, err
+}
+
+func (cfg *verifyConfig) verifyWith(sig *Signature, signingInput []byte, candidate verifyCandidate) error {
+
+	if err := cfg.checkAlgorithm(sig.Protected, candidate.alg, candidate.key); err != nil {
+		return errors.Wrap(err, `invalid algorithm`)
+	}
+	verifier, err := verify.New(candidate.alg)
+	if err != nil {
+		return errors.Wrap(err, "failed to create verifier")
+	}
+	return verifier.Verify(signingInput, sig.Signature, candidate.key)
+}
+
+// checkAlgorithm enforces the strict algorithm mode enabled by the
+// WithAllowedAlgorithms option
+func (cfg *verifyConfig) checkAlgorithm(protected Headers, alg jwa.SignatureAlgorithm, key interface{}) error {
+
+	if cfg.allowedAlgorithms == nil {
+		return nil
+	}
+	if protected == nil || protected.GetAlgorithm() == jwa.NoValue {
+		return errors.Errorf(`%s header parameter must be protected`, AlgorithmKey)
+	}
+	hdrAlg := protected.GetAlgorithm()
+	if _, ok := cfg.allowedAlgorithms[hdrAlg]; !ok {
+		return errors.Errorf(`algorithm %s is not allowed`, hdrAlg)
+	}
+	if hdrAlg != alg {
+		return errors.Errorf(`algorithm %s does not match %s header parameter %s`, alg, AlgorithmKey, hdrAlg)
+	}
+	return checkKeyType(hdrAlg, key)
+}
@@ -0,0 +1,434 @@
+package jws_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jwk"
+	"github.com/repenno/jwx-opa/jws"
+)
+
+func TestAllowedAlgorithms(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+	sharedKey := []byte("Avracadabra")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+
+	signRSA, err := jws.SignWithOption(payload, jwa.RS256, rsaKey)
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %s", err.Error())
+	}
+	sign := func(t *testing.T, hdr string) []byte {
+		t.Helper()
+		signed, err := jws.SignLiteral(payload, jwa.HS256, sharedKey, []byte(hdr))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		return signed
+	}
+
+	t.Run("Allowed algorithm", func(t *testing.T) {
+		verified, err := jws.Verify(signRSA, jwa.RS256, &rsaKey.PublicKey, jws.WithAllowedAlgorithms(jwa.RS256, jwa.ES256))
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		if string(verified) != string(payload) {
+			t.Fatal("Mismatched payload")
+		}
+	})
+	t.Run("Algorithm not allowed", func(t *testing.T) {
+		signed := sign(t, `{"alg":"HS256"}`)
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey); err != nil {
+			t.Fatalf("Verification without allowed algorithms failed: %s", err.Error())
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey, jws.WithAllowedAlgorithms(jwa.RS256)); err == nil {
+			t.Fatal("Verification with an algorithm that is not allowed should fail")
+		}
+	})
+	t.Run("Missing algorithm", func(t *testing.T) {
+		signed := sign(t, `{"typ":"JWT"}`)
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey); err != nil {
+			t.Fatalf("Verification without allowed algorithms failed: %s", err.Error())
+		}
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey, jws.WithAllowedAlgorithms(jwa.HS256)); err == nil {
+			t.Fatal("Verification without a protected alg header should fail")
+		}
+	})
+	t.Run("Mismatched algorithm", func(t *testing.T) {
+		signed := sign(t, `{"alg":"HS512"}`)
+		if _, err := jws.Verify(signed, jwa.HS256, sharedKey, jws.WithAllowedAlgorithms(jwa.HS256, jwa.HS512)); err == nil {
+			t.Fatal("Verification with an algorithm other than the alg header should fail")
+		}
+	})
+	t.Run("Mismatched key type", func(t *testing.T) {
+		signed := sign(t, `{"alg":"HS256"}`)
+		if _, err := jws.Verify(signed, jwa.HS256, &rsaKey.PublicKey, jws.WithAllowedAlgorithms(jwa.HS256)); err == nil {
+			t.Fatal("Verification with a key of the wrong type should fail")
+		}
+	})
+	t.Run("JWK without algorithm", func(t *testing.T) {
+		jwkKey, err := jwk.New(&rsaKey.PublicKey)
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		if _, err := jws.VerifyWithJWK(signRSA, jwkKey); err == nil {
+			t.Fatal("Verification with a JWK without alg should fail")
+		}
+		if _, err := jws.VerifyWithJWK(signRSA, jwkKey, jws.WithAllowedAlgorithms(jwa.RS256)); err != nil {
+			t.Fatalf("Verification using the alg header failed: %s", err.Error())
+		}
+		if _, err := jws.VerifyWithJWK(signRSA, jwkKey, jws.WithAllowedAlgorithms(jwa.PS256)); err == nil {
+			t.Fatal("Verification with an algorithm that is not allowed should fail")
+		}
+	})
+}
+
+func TestKeySelection(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	var keys []*rsa.PrivateKey
+	var set jwk.Set
+	for _, kid := range []string{"first", "second", "third"} {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %s", err.Error())
+		}
+		jwkKey, err := jwk.New(&key.PublicKey)
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		if err := jwkKey.Set(jwk.KeyIDKey, kid); err != nil {
+			t.Fatalf("Failed to set kid: %s", err.Error())
+		}
+		if err := jwkKey.Set(jwk.AlgorithmKey, jwa.RS256); err != nil {
+			t.Fatalf("Failed to set alg: %s", err.Error())
+		}
+		keys = append(keys, key)
+		set.Keys = append(set.Keys, jwkKey)
+	}
+	sign := func(t *testing.T, hdr string, key *rsa.PrivateKey) []byte {
+		t.Helper()
+		signed, err := jws.SignLiteral(payload, jwa.RS256, key, []byte(hdr))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		return signed
+	}
+
+	t.Run("Matching kid", func(t *testing.T) {
+		signed := sign(t, `{"alg":"RS256","kid":"second"}`, keys[1])
+		verified, err := jws.VerifyWithJWKSet(signed, &set)
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		if string(verified) != string(payload) {
+			t.Fatal("Mismatched payload")
+		}
+	})
+	t.Run("Wrong kid", func(t *testing.T) {
+		signed := sign(t, `{"alg":"RS256","kid":"first"}`, keys[1])
+		if _, err := jws.VerifyWithJWKSet(signed, &set, jws.WithKeyFallback()); err == nil {
+			t.Fatal("Verification should only use the key matching the kid")
+		}
+	})
+	t.Run("Unknown kid", func(t *testing.T) {
+		signed := sign(t, `{"alg":"RS256","kid":"fourth"}`, keys[2])
+		if _, err := jws.VerifyWithJWKSet(signed, &set); err == nil {
+			t.Fatal("Verification with an unknown kid should fail without the fallback")
+		}
+		if _, err := jws.VerifyWithJWKSet(signed, &set, jws.WithKeyFallback()); err != nil {
+			t.Fatalf("Verification with the fallback failed: %s", err.Error())
+		}
+	})
+	t.Run("Key accept function", func(t *testing.T) {
+		signed := sign(t, `{"alg":"RS256","kid":"third"}`, keys[2])
+		reject := func(key jwk.Key) bool {
+			return key.GetKeyID() != "third"
+		}
+		if _, err := jws.VerifyWithJWKSet(signed, &set, jws.WithKeyAccept(reject), jws.WithKeyFallback()); err == nil {
+			t.Fatal("Verification with a rejected key should fail")
+		}
+	})
+	t.Run("Key usage", func(t *testing.T) {
+		signed := sign(t, `{"alg":"RS256","kid":"first"}`, keys[0])
+		jwkKey, err := jwk.New(&keys[0].PublicKey)
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		jwkKey.Set(jwk.KeyIDKey, "first")
+		jwkKey.Set(jwk.AlgorithmKey, jwa.RS256)
+		jwkKey.Set(jwk.KeyUsageKey, string(jwk.ForEncryption))
+		if _, err := jws.VerifyWithJWKSet(signed, &jwk.Set{Keys: []jwk.Key{jwkKey}}); err == nil {
+			t.Fatal("Verification with an encryption key should fail")
+		}
+		jwkKey.Set(jwk.KeyUsageKey, string(jwk.ForSignature))
+		jwkKey.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpSign})
+		if _, err := jws.VerifyWithJWKSet(signed, &jwk.Set{Keys: []jwk.Key{jwkKey}}); err == nil {
+			t.Fatal("Verification with a key not allowed to verify should fail")
+		}
+		jwkKey.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpVerify})
+		if _, err := jws.VerifyWithJWKSet(signed, &jwk.Set{Keys: []jwk.Key{jwkKey}}); err != nil {
+			t.Fatalf("Verification with a signature key failed: %s", err.Error())
+		}
+	})
+	t.Run("Key algorithm", func(t *testing.T) {
+		signed, err := jws.SignLiteral(payload, jwa.PS256, keys[0], []byte(`{"alg":"PS256","kid":"first"}`))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if _, err := jws.VerifyWithJWKSet(signed, &set); err == nil {
+			t.Fatal("Verification with a key declaring another alg should fail")
+		}
+	})
+}
+
+func TestEmbeddedJWK(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	sign := func(t *testing.T, embedded interface{}) []byte {
+		t.Helper()
+		jwkKey, err := jwk.New(embedded)
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		var hdr jws.StandardHeaders
+		hdr.Set(jws.AlgorithmKey, jwa.ES256)
+		if err := hdr.Set(jws.JWKKey, jwkKey); err != nil {
+			t.Fatalf("Failed to set jwk: %s", err.Error())
+		}
+		hdrBuf, err := json.Marshal(hdr)
+		if err != nil {
+			t.Fatalf("Failed to marshal Headers: %s", err.Error())
+		}
+		signed, err := jws.SignLiteral(payload, jwa.ES256, ecKey, hdrBuf)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		return signed
+	}
+	publicJWK, err := jwk.New(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to create JWK: %s", err.Error())
+	}
+	thumbprint, err := jwk.Thumbprint(publicJWK, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Failed to compute thumbprint: %s", err.Error())
+	}
+	signed := sign(t, &ecKey.PublicKey)
+
+	t.Run("Parse", func(t *testing.T) {
+		msg, err := jws.ParseByte(signed)
+		if err != nil {
+			t.Fatalf("Failed to parse message: %s", err.Error())
+		}
+		v, ok := msg.GetSignatures()[0].ProtectedHeaders().Get(jws.JWKKey)
+		if !ok {
+			t.Fatal("Missing embedded JWK")
+		}
+		tp, err := jwk.Thumbprint(v.(jwk.Key), crypto.SHA256)
+		if err != nil || !bytes.Equal(tp, thumbprint) {
+			t.Fatal("Mismatched embedded JWK")
+		}
+	})
+	t.Run("Untrusted key", func(t *testing.T) {
+		if _, err := jws.VerifyWithEmbeddedJWK(signed); err == nil {
+			t.Fatal("Verification with an untrusted embedded key should fail")
+		}
+		other := make([]byte, len(thumbprint))
+		if _, err := jws.VerifyWithEmbeddedJWK(signed, jws.WithTrustedThumbprints(crypto.SHA256, other)); err == nil {
+			t.Fatal("Verification with an unknown thumbprint should fail")
+		}
+	})
+	t.Run("Trusted thumbprint", func(t *testing.T) {
+		verified, err := jws.VerifyWithEmbeddedJWK(signed, jws.WithTrustedThumbprints(crypto.SHA256, thumbprint))
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		if string(verified) != string(payload) {
+			t.Fatal("Mismatched payload")
+		}
+		msg, err := jws.VerifyMessageWithEmbeddedJWK(signed, jws.WithTrustedThumbprints(crypto.SHA256, thumbprint))
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		if msg.VerifiedJWK() == nil {
+			t.Fatal("Expected the message to be verified with the embedded JWK")
+		}
+		tp, err := jwk.Thumbprint(msg.VerifiedJWK(), crypto.SHA256)
+		if err != nil || !bytes.Equal(tp, thumbprint) {
+			t.Fatal("Mismatched verified JWK")
+		}
+	})
+	t.Run("Trusted key function", func(t *testing.T) {
+		trust := func(key jwk.Key) bool {
+			tp, err := jwk.Thumbprint(key, crypto.SHA256)
+			return err == nil && bytes.Equal(tp, thumbprint)
+		}
+		if _, err := jws.VerifyWithEmbeddedJWK(signed, jws.WithTrustedKeyFunc(trust)); err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		reject := func(jwk.Key) bool { return false }
+		if _, err := jws.VerifyWithEmbeddedJWK(signed, jws.WithTrustedKeyFunc(reject)); err == nil {
+			t.Fatal("Verification with a rejected embedded key should fail")
+		}
+	})
+	t.Run("Embedded private key", func(t *testing.T) {
+		// Such Headers cannot be marshaled, and are crafted by hand
+		privateJWK, err := jwk.New(ecKey)
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		jwkBuf, err := json.Marshal(privateJWK)
+		if err != nil {
+			t.Fatalf("Failed to marshal JWK: %s", err.Error())
+		}
+		signed, err := jws.SignLiteral(payload, jwa.ES256, ecKey, []byte(`{"alg":"ES256","jwk":`+string(jwkBuf)+`}`))
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		if _, err := jws.VerifyWithEmbeddedJWK(signed, jws.WithTrustedThumbprints(crypto.SHA256, thumbprint)); err == nil {
+			t.Fatal("Verification with an embedded private key should fail")
+		}
+	})
+}
+
+func TestVerifyMessage(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	msg, err := jws.SignMulti(payload,
+		jws.SignerConfig{Algorithm: jwa.RS256, Key: rsaKey, Protected: &jws.StandardHeaders{KeyID: "rsa-key"}},
+		jws.SignerConfig{Algorithm: jwa.ES256, Key: ecKey, Protected: &jws.StandardHeaders{KeyID: "ec-key", Type: "JWT"}},
+	)
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %s", err.Error())
+	}
+	signed, err := jws.SerializeJSON(msg)
+	if err != nil {
+		t.Fatalf("Failed to serialize message: %s", err.Error())
+	}
+
+	t.Run("Verified signature", func(t *testing.T) {
+		verified, err := jws.VerifyMessage(signed, jwa.ES256, &ecKey.PublicKey)
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		if string(verified.GetPayload()) != string(payload) {
+			t.Fatal("Mismatched payload")
+		}
+		if len(verified.GetSignatures()) != 1 {
+			t.Fatalf("Expected only the verified signature, got %d", len(verified.GetSignatures()))
+		}
+		hdr := verified.GetSignatures()[0].ProtectedHeaders()
+		if kid, _ := hdr.Get(jws.KeyIDKey); kid != "ec-key" {
+			t.Fatalf("Expected the Headers of the verified signature, got kid %v", kid)
+		}
+		if typ, _ := hdr.Get(jws.TypeKey); typ != "JWT" {
+			t.Fatalf("Expected typ JWT, got %v", typ)
+		}
+		if verified.VerifiedAlgorithm() != jwa.ES256 {
+			t.Fatalf("Unexpected verified algorithm %s", verified.VerifiedAlgorithm())
+		}
+		if verified.VerifiedKey() != &ecKey.PublicKey {
+			t.Fatal("Unexpected verified key")
+		}
+		if verified.VerifiedJWK() != nil {
+			t.Fatal("Message was not verified with a JWK")
+		}
+	})
+	t.Run("JWK set", func(t *testing.T) {
+		var set jwk.Set
+		for kid, key := range map[string]interface{}{"rsa-key": &rsaKey.PublicKey, "ec-key": &ecKey.PublicKey} {
+			jwkKey, err := jwk.New(key)
+			if err != nil {
+				t.Fatalf("Failed to create JWK: %s", err.Error())
+			}
+			if err := jwkKey.Set(jwk.KeyIDKey, kid); err != nil {
+				t.Fatalf("Failed to set kid: %s", err.Error())
+			}
+			set.Keys = append(set.Keys, jwkKey)
+		}
+		verified, err := jws.VerifyMessageWithJWKSet(signed, &set, jws.WithAllowedAlgorithms(jwa.RS256, jwa.ES256))
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		if verified.VerifiedJWK() == nil || verified.VerifiedJWK().GetKeyID() != "rsa-key" {
+			t.Fatal("Expected the message to be verified with the rsa-key JWK")
+		}
+		if kid, _ := verified.GetSignatures()[0].ProtectedHeaders().Get(jws.KeyIDKey); kid != "rsa-key" {
+			t.Fatalf("Expected the Headers of the verified signature, got kid %v", kid)
+		}
+	})
+	t.Run("JWK", func(t *testing.T) {
+		key, err := jwk.New(&ecKey.PublicKey)
+		if err != nil {
+			t.Fatalf("Failed to create JWK: %s", err.Error())
+		}
+		verified, err := jws.VerifyMessageWithJWK(signed, key, jws.WithAllowedAlgorithms(jwa.ES256))
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		if verified.VerifiedJWK() != key {
+			t.Fatal("Expected the message to be verified with the JWK")
+		}
+		if kid, _ := verified.GetSignatures()[0].ProtectedHeaders().Get(jws.KeyIDKey); kid != "ec-key" {
+			t.Fatalf("Expected the Headers of the verified signature, got kid %v", kid)
+		}
+	})
+	t.Run("Unprotected Headers", func(t *testing.T) {
+		var unprotected jws.StandardHeaders
+		if err := unprotected.Set(jws.KeyIDKey, "attacker-key"); err != nil {
+			t.Fatalf("Failed to set kid: %s", err.Error())
+		}
+		if err := unprotected.Set("role", "admin"); err != nil {
+			t.Fatalf("Failed to set private header parameter: %s", err.Error())
+		}
+		flattened, err := jws.SignFlattened(payload, jwa.ES256, ecKey, nil, &unprotected)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		verified, err := jws.VerifyMessage(flattened, jwa.ES256, &ecKey.PublicKey)
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		sig := verified.GetSignatures()[0]
+		if sig.PublicHeaders() != nil {
+			t.Fatal("Unprotected Headers should not be returned")
+		}
+		if kid, ok := sig.ProtectedHeaders().Get(jws.KeyIDKey); ok {
+			t.Fatalf("Unexpected kid %v", kid)
+		}
+		if len(sig.GetSignature()) == 0 || sig.RawProtectedHeaders() == nil {
+			t.Fatal("Expected the verified signature")
+		}
+	})
+	t.Run("Failure", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %s", err.Error())
+		}
+		if verified, err := jws.VerifyMessage(signed, jwa.ES256, &otherKey.PublicKey); err == nil || verified != nil {
+			t.Fatal("Verification with the wrong key should fail")
+		}
+	})
+}
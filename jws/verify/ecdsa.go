@@ -3,64 +3,115 @@ package verify
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"hash"
 	"math/big"
 
 	"github.com/pkg/errors"
 	"github.com/repenno/jwx-opa/jwa"
 )
 
-var ecdsaVerifyFuncs = map[jwa.SignatureAlgorithm]ecdsaVerifyFunc{}
-
-func init() {
-	algs := map[jwa.SignatureAlgorithm]crypto.Hash{
-		jwa.ES256: crypto.SHA256,
-		jwa.ES384: crypto.SHA384,
-		jwa.ES512: crypto.SHA512,
-	}
-
-	for alg, h := range algs {
-		ecdsaVerifyFuncs[alg] = makeECDSAVerifyFunc(h)
-	}
+var ecdsaHashes = map[jwa.SignatureAlgorithm]crypto.Hash{
+	jwa.ES256:  crypto.SHA256,
+	jwa.ES256K: crypto.SHA256,
+	jwa.ES384:  crypto.SHA384,
+	jwa.ES512:  crypto.SHA512,
+	jwa.ESB256: crypto.SHA256,
+	jwa.ESB384: crypto.SHA384,
+	jwa.ESB512: crypto.SHA512,
 }
 
-func makeECDSAVerifyFunc(hash crypto.Hash) ecdsaVerifyFunc {
-	return ecdsaVerifyFunc(func(payload []byte, signature []byte, key *ecdsa.PublicKey) error {
+// ecdsaCurves maps the algorithms to the name of the only curve they may
+// be used with, so that a signature cannot be passed off as one of another
+// algorithm using the same hash function
+var ecdsaCurves = map[jwa.SignatureAlgorithm]string{
+	jwa.ES256:  "P-256",
+	jwa.ES256K: "secp256k1",
+	jwa.ES384:  "P-384",
+	jwa.ES512:  "P-521",
+	jwa.ESB256: "brainpoolP256r1",
+	jwa.ESB384: "brainpoolP384r1",
+	jwa.ESB512: "brainpoolP512r1",
+}
 
-		r, s := &big.Int{}, &big.Int{}
-		n := len(signature) / 2
-		r.SetBytes(signature[:n])
-		s.SetBytes(signature[n:])
+func verifyECDSA(digest []byte, signature []byte, key *ecdsa.PublicKey) error {
 
-		h := hash.New()
-		h.Write(payload)
+	// r and s are both as long as the order of the curve
+	keyBytes := (key.Curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*keyBytes {
+		return errors.Errorf(`invalid ecdsa signature length %d, expected %d`, len(signature), 2*keyBytes)
+	}
+	r, s := &big.Int{}, &big.Int{}
+	n := keyBytes
+	r.SetBytes(signature[:n])
+	s.SetBytes(signature[n:])
 
-		if !ecdsa.Verify(key, h.Sum(nil), r, s) {
-			return errors.New(`failed to verify signature using ecdsa`)
-		}
-		return nil
-	})
+	if !ecdsa.Verify(key, digest, r, s) {
+		return errors.New(`failed to verify signature using ecdsa`)
+	}
+	return nil
 }
 
 func newECDSA(alg jwa.SignatureAlgorithm) (*ECDSAVerifier, error) {
-	verifyfn, ok := ecdsaVerifyFuncs[alg]
+	h, ok := ecdsaHashes[alg]
 	if !ok {
 		return nil, errors.Errorf(`unsupported algorithm while trying to create ECDSA verifier: %s`, alg)
 	}
 
 	return &ECDSAVerifier{
-		verify: verifyfn,
+		alg:    alg,
+		hash:   h,
+		curve:  ecdsaCurves[alg],
+		verify: verifyECDSA,
 	}, nil
 }
 
 // Verify checks whether the signature for a given input and key is correct
 func (v ECDSAVerifier) Verify(payload []byte, signature []byte, key interface{}) error {
+	h, err := v.NewHash(key)
+	if err != nil {
+		return err
+	}
+	h.Write(payload)
+	return v.VerifyHash(h, signature, key)
+}
+
+// NewHash creates the hash.Hash the payload is written to before calling VerifyHash
+func (v ECDSAVerifier) NewHash(key interface{}) (hash.Hash, error) {
+	if _, err := v.publicKey(key); err != nil {
+		return nil, err
+	}
+	return v.hash.New(), nil
+}
+
+// VerifyHash checks whether the signature for the input written to `h` and key is correct
+func (v ECDSAVerifier) VerifyHash(h hash.Hash, signature []byte, key interface{}) error {
+	ecdsakey, err := v.publicKey(key)
+	if err != nil {
+		return err
+	}
+	return v.verify(h.Sum(nil), signature, ecdsakey)
+}
+
+// publicKey returns `key` as an *ecdsa.PublicKey, provided that its curve
+// is the one of the algorithm
+func (v ECDSAVerifier) publicKey(key interface{}) (*ecdsa.PublicKey, error) {
+	ecdsakey, err := ecdsaPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if name := ecdsakey.Curve.Params().Name; name != v.curve {
+		return nil, errors.Errorf(`curve %s cannot be used with algorithm %s`, name, v.alg)
+	}
+	return ecdsakey, nil
+}
+
+func ecdsaPublicKey(key interface{}) (*ecdsa.PublicKey, error) {
 	if key == nil {
-		return errors.New(`missing public key while verifying payload`)
+		return nil, errors.New(`missing public key while verifying payload`)
 	}
 	ecdsakey, ok := key.(*ecdsa.PublicKey)
 	if !ok {
-		return errors.Errorf(`invalid key type %T. *ecdsa.PublicKey is required`, key)
+		return nil, errors.Errorf(`invalid key type %T. *ecdsa.PublicKey is required`, key)
 	}
-
-	return v.verify(payload, signature, ecdsakey)
+	return ecdsakey, nil
 }
@@ -1,10 +1,32 @@
 package verify
 
 import (
-	"github.com/repenno/jwx-opa/jwa"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"testing"
+
+	"github.com/repenno/jwx-opa/curves"
+	"github.com/repenno/jwx-opa/jwa"
 )
 
+// signECDSA signs the SHA-256 digest of `payload`, with each of r and s
+// padded to `size` octets
+func signECDSA(t *testing.T, key *ecdsa.PrivateKey, payload []byte, size int) []byte {
+	t.Helper()
+	digest := sha256.Sum256(payload)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %s", err.Error())
+	}
+	signature := make([]byte, 2*size)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(signature[size-len(rBytes):size], rBytes)
+	copy(signature[2*size-len(sBytes):], sBytes)
+	return signature
+}
+
 func TestECDSAVerify(t *testing.T) {
 	type dummyStruct struct {
 		dummy1 int
@@ -31,4 +53,47 @@ func TestECDSAVerify(t *testing.T) {
 			t.Fatal("ECDSA Verification should fail")
 		}
 	})
+	t.Run("Curve binding", func(t *testing.T) {
+		payload := []byte("payload")
+		keys := map[jwa.SignatureAlgorithm]elliptic.Curve{
+			jwa.ES256:  elliptic.P256(),
+			jwa.ES256K: curves.Secp256k1(),
+			jwa.ESB256: curves.BrainpoolP256r1(),
+		}
+		for alg, curve := range keys {
+			key, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %s", err.Error())
+			}
+			signature := signECDSA(t, key, payload, 32)
+			for verifierAlg := range keys {
+				verifier, err := newECDSA(verifierAlg)
+				if err != nil {
+					t.Fatalf("Verifier creation failure: %s", err.Error())
+				}
+				err = verifier.Verify(payload, signature, &key.PublicKey)
+				if verifierAlg == alg && err != nil {
+					t.Fatalf("%s verification failed: %s", alg, err.Error())
+				}
+				if verifierAlg != alg && err == nil {
+					t.Fatalf("%s verification with a %s key should fail", verifierAlg, curve.Params().Name)
+				}
+			}
+		}
+	})
+	t.Run("Signature length", func(t *testing.T) {
+		payload := []byte("payload")
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %s", err.Error())
+		}
+		verifier, err := newECDSA(jwa.ES256)
+		if err != nil {
+			t.Fatalf("Verifier creation failure: %s", err.Error())
+		}
+		// r and s padded with an extra zero octet still hold the same values
+		if err := verifier.Verify(payload, signECDSA(t, key, payload, 33), &key.PublicKey); err == nil {
+			t.Fatal("Verification of an oversized signature should fail")
+		}
+	})
 }
@@ -0,0 +1,30 @@
+package verify
+
+import (
+	"crypto/ed25519"
+
+	"github.com/pkg/errors"
+)
+
+func newEdDSA() (*EdDSAVerifier, error) {
+	return &EdDSAVerifier{}, nil
+}
+
+// Verify checks whether the signature for a given input and Ed25519
+// public key is correct
+func (v EdDSAVerifier) Verify(payload, signature []byte, key interface{}) error {
+	if key == nil {
+		return errors.New(`missing public key while verifying payload`)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return errors.Errorf(`invalid key type %T. ed25519.PublicKey is required`, key)
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return errors.New(`invalid ed25519 public key size`)
+	}
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return errors.New(`failed to verify signature using eddsa`)
+	}
+	return nil
+}
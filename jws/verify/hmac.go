@@ -2,6 +2,8 @@ package verify
 
 import (
 	"crypto/hmac"
+	"hash"
+
 	"github.com/pkg/errors"
 	"github.com/repenno/jwx-opa/jwa"
 	"github.com/repenno/jwx-opa/jws/sign"
@@ -29,3 +31,22 @@ func (v HMACVerifier) Verify(signingInput, signature []byte, key interface{}) (e
 	}
 	return nil
 }
+
+// NewHash creates the keyed hash.Hash the input is written to before calling VerifyHash
+func (v HMACVerifier) NewHash(key interface{}) (hash.Hash, error) {
+
+	hs, ok := v.signer.(sign.HashSigner)
+	if !ok {
+		return nil, errors.Errorf(`signer for %s cannot sign incrementally`, v.signer.Algorithm())
+	}
+	return hs.NewHash(key)
+}
+
+// VerifyHash checks whether the signature for the input written to `h` and key is correct
+func (v HMACVerifier) VerifyHash(h hash.Hash, signature []byte, key interface{}) error {
+
+	if !hmac.Equal(signature, h.Sum(nil)) {
+		return errors.New(`failed to match hmac signature`)
+	}
+	return nil
+}
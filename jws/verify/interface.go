@@ -1,9 +1,12 @@
 package verify
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
+	"hash"
 
+	"github.com/repenno/jwx-opa/jwa"
 	"github.com/repenno/jwx-opa/jws/sign"
 )
 
@@ -19,17 +22,38 @@ type Verifier interface {
 	Verify(payload []byte, signature []byte, key interface{}) error
 }
 
+// HashVerifier is implemented by verifiers that can check a signature
+// incrementally, by writing the payload to a hash.Hash instead of
+// holding it in memory
+type HashVerifier interface {
+	Verifier
+
+	// NewHash creates the hash.Hash that the payload to be verified
+	// with `key` is written to
+	NewHash(key interface{}) (hash.Hash, error)
+	// VerifyHash checks whether the signature is valid for the payload
+	// written to `h`, which must have been created by NewHash for the
+	// same `key`
+	VerifyHash(h hash.Hash, signature []byte, key interface{}) error
+}
+
+// rsaVerifyFunc verifies the signature of the digest of a payload
 type rsaVerifyFunc func([]byte, []byte, *rsa.PublicKey) error
 
 // RSAVerifier implements the Verifier interface
 type RSAVerifier struct {
+	hash   crypto.Hash
 	verify rsaVerifyFunc
 }
 
+// ecdsaVerifyFunc verifies the signature of the digest of a payload
 type ecdsaVerifyFunc func([]byte, []byte, *ecdsa.PublicKey) error
 
 // ECDSAVerifier implements the Verifier interface
 type ECDSAVerifier struct {
+	alg    jwa.SignatureAlgorithm
+	hash   crypto.Hash
+	curve  string // Name of the curve the algorithm is bound to
 	verify ecdsaVerifyFunc
 }
 
@@ -37,3 +61,15 @@ type ECDSAVerifier struct {
 type HMACVerifier struct {
 	signer sign.Signer
 }
+
+// NoneVerifier implements the Verifier interface for unsecured JWS
+// messages
+type NoneVerifier struct{}
+
+// EdDSAVerifier implements the Verifier interface
+type EdDSAVerifier struct{}
+
+// MLDSAVerifier implements the Verifier interface
+type MLDSAVerifier struct {
+	alg jwa.SignatureAlgorithm
+}
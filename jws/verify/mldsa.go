@@ -0,0 +1,31 @@
+package verify
+
+import (
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/mldsa"
+)
+
+func newMLDSA(alg jwa.SignatureAlgorithm) (*MLDSAVerifier, error) {
+	return &MLDSAVerifier{alg: alg}, nil
+}
+
+// Verify checks whether the signature for a given input and ML-DSA
+// public key is correct. The parameter set of the key must match the
+// algorithm.
+func (v MLDSAVerifier) Verify(payload, signature []byte, key interface{}) error {
+	if key == nil {
+		return errors.New(`missing public key while verifying payload`)
+	}
+	publicKey, ok := key.(*mldsa.PublicKey)
+	if !ok {
+		return errors.Errorf(`invalid key type %T. *mldsa.PublicKey is required`, key)
+	}
+	if name := publicKey.Parameters().Name(); name != string(v.alg) {
+		return errors.Errorf(`%s key cannot be used with algorithm %s`, name, v.alg)
+	}
+	if !mldsa.Verify(publicKey, payload, signature, nil) {
+		return errors.New(`failed to verify signature using ml-dsa`)
+	}
+	return nil
+}
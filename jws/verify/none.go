@@ -0,0 +1,23 @@
+package verify
+
+import (
+	"github.com/pkg/errors"
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jws/sign"
+)
+
+func newNone() (*NoneVerifier, error) {
+	return &NoneVerifier{}, nil
+}
+
+// Verify checks that an unsecured JWS message has an empty signature.
+// `key` must be sign.UnsafeAllowNone, otherwise the verification fails.
+func (v NoneVerifier) Verify(payload, signature []byte, key interface{}) error {
+	if key != sign.UnsafeAllowNone {
+		return errors.Errorf(`algorithm %s requires the UnsafeAllowNone key`, jwa.NoSignature)
+	}
+	if len(signature) != 0 {
+		return errors.New(`unsecured JWS must have an empty signature`)
+	}
+	return nil
+}
@@ -3,12 +3,14 @@ package verify
 import (
 	"crypto"
 	"crypto/rsa"
+	"hash"
 
 	"github.com/pkg/errors"
 	"github.com/repenno/jwx-opa/jwa"
 )
 
 var rsaVerifyFuncs = map[jwa.SignatureAlgorithm]rsaVerifyFunc{}
+var rsaHashes = map[jwa.SignatureAlgorithm]crypto.Hash{}
 
 func init() {
 	algs := map[jwa.SignatureAlgorithm]struct {
@@ -43,22 +45,19 @@ func init() {
 
 	for alg, item := range algs {
 		rsaVerifyFuncs[alg] = item.VerifyFunc(item.Hash)
+		rsaHashes[alg] = item.Hash
 	}
 }
 
 func makeVerifyPKCS1v15(hash crypto.Hash) rsaVerifyFunc {
-	return rsaVerifyFunc(func(payload, signature []byte, key *rsa.PublicKey) error {
-		h := hash.New()
-		h.Write(payload)
-		return rsa.VerifyPKCS1v15(key, hash, h.Sum(nil), signature)
+	return rsaVerifyFunc(func(digest, signature []byte, key *rsa.PublicKey) error {
+		return rsa.VerifyPKCS1v15(key, hash, digest, signature)
 	})
 }
 
 func makeVerifyPSS(hash crypto.Hash) rsaVerifyFunc {
-	return rsaVerifyFunc(func(payload, signature []byte, key *rsa.PublicKey) error {
-		h := hash.New()
-		h.Write(payload)
-		return rsa.VerifyPSS(key, hash, h.Sum(nil), signature, nil)
+	return rsaVerifyFunc(func(digest, signature []byte, key *rsa.PublicKey) error {
+		return rsa.VerifyPSS(key, hash, digest, signature, nil)
 	})
 }
 
@@ -69,19 +68,45 @@ func newRSA(alg jwa.SignatureAlgorithm) (*RSAVerifier, error) {
 	}
 
 	return &RSAVerifier{
+		hash:   rsaHashes[alg],
 		verify: verifyfn,
 	}, nil
 }
 
 // Verify checks if a JWS is valid.
 func (v RSAVerifier) Verify(payload, signature []byte, key interface{}) error {
+	h, err := v.NewHash(key)
+	if err != nil {
+		return err
+	}
+	h.Write(payload)
+	return v.VerifyHash(h, signature, key)
+}
+
+// NewHash creates the hash.Hash the payload is written to before calling VerifyHash
+func (v RSAVerifier) NewHash(key interface{}) (hash.Hash, error) {
+	if _, err := rsaPublicKey(key); err != nil {
+		return nil, err
+	}
+	return v.hash.New(), nil
+}
+
+// VerifyHash checks if a JWS is valid for the payload written to `h`
+func (v RSAVerifier) VerifyHash(h hash.Hash, signature []byte, key interface{}) error {
+	rsaKey, err := rsaPublicKey(key)
+	if err != nil {
+		return err
+	}
+	return v.verify(h.Sum(nil), signature, rsaKey)
+}
+
+func rsaPublicKey(key interface{}) (*rsa.PublicKey, error) {
 	if key == nil {
-		return errors.New(`missing public key while verifying payload`)
+		return nil, errors.New(`missing public key while verifying payload`)
 	}
 	rsaKey, ok := key.(*rsa.PublicKey)
 	if !ok {
-		return errors.Errorf(`invalid key type %T. *rsa.PublicKey is required`, key)
+		return nil, errors.Errorf(`invalid key type %T. *rsa.PublicKey is required`, key)
 	}
-
-	return v.verify(payload, signature, rsaKey)
+	return rsaKey, nil
 }
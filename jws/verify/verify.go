@@ -1,20 +1,78 @@
 package verify
 
 import (
+	"sync"
+
 	"github.com/pkg/errors"
 	"github.com/repenno/jwx-opa/jwa"
 )
 
+// VerifierFactory creates the Verifier of an algorithm added with Register
+type VerifierFactory func(alg jwa.SignatureAlgorithm) (Verifier, error)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[jwa.SignatureAlgorithm]VerifierFactory{}
+)
+
 // New creates a new JWS verifier using the specified algorithm
-// and the public key
+// and the public key. Algorithms added with Register are supported
+// as well.
 func New(alg jwa.SignatureAlgorithm) (Verifier, error) {
+	registryMutex.RLock()
+	factory, ok := registry[alg]
+	registryMutex.RUnlock()
+	if ok {
+		return factory(alg)
+	}
+	return newBuiltin(alg)
+}
+
+// Register makes New create verifiers for `alg` using `factory`. The name
+// of the algorithm is to be registered with jwa.RegisterSignatureAlgorithm
+// as well, so that it is accepted in header parameters. The algorithms
+// supported by this package cannot be replaced. The factory is removed
+// with Unregister.
+func Register(alg jwa.SignatureAlgorithm, factory VerifierFactory) error {
+	if factory == nil {
+		return errors.Errorf(`missing verifier factory for %s`, alg)
+	}
+	if _, err := newBuiltin(alg); err == nil {
+		return errors.Errorf(`signature algorithm %s cannot be replaced`, alg)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[alg] = factory
+	return nil
+}
+
+// Unregister removes the verifier factory of `alg` added with Register
+func Unregister(alg jwa.SignatureAlgorithm) error {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := registry[alg]; !ok {
+		return errors.Errorf(`signature algorithm %s is not registered`, alg)
+	}
+	delete(registry, alg)
+	return nil
+}
+
+// newBuiltin creates the verifier of an algorithm supported by this package
+func newBuiltin(alg jwa.SignatureAlgorithm) (Verifier, error) {
 	switch alg {
 	case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512:
 		return newRSA(alg)
-	case jwa.ES256, jwa.ES384, jwa.ES512:
+	case jwa.ES256, jwa.ES256K, jwa.ES384, jwa.ES512, jwa.ESB256, jwa.ESB384, jwa.ESB512:
 		return newECDSA(alg)
 	case jwa.HS256, jwa.HS384, jwa.HS512:
 		return newHMAC(alg)
+	case jwa.EdDSA:
+		return newEdDSA()
+	case jwa.MLDSA44, jwa.MLDSA65, jwa.MLDSA87:
+		return newMLDSA(alg)
+	case jwa.NoSignature:
+		return newNone()
 	default:
 		return nil, errors.Errorf(`unsupported signature algorithm: %s`, alg)
 	}
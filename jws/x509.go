@@ -0,0 +1,114 @@
+package jws
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// CertificateChain is the X.509 certificate chain found in the "x5c"
+// header parameter. The first certificate holds the key used to sign
+// the message, and each of the following ones certifies the previous one.
+type CertificateChain []*x509.Certificate
+
+// MarshalJSON serializes the chain as an array of base64 encoded DER
+// certificates, as described in https://tools.ietf.org/html/rfc7515#section-4.1.6
+func (c CertificateChain) MarshalJSON() ([]byte, error) {
+	certs := make([]string, len(c))
+	for i, cert := range c {
+		certs[i] = base64.StdEncoding.EncodeToString(cert.Raw)
+	}
+	return json.Marshal(certs)
+}
+
+// UnmarshalJSON parses an array of base64 encoded DER certificates
+func (c *CertificateChain) UnmarshalJSON(buf []byte) error {
+	var certs []string
+	if err := json.Unmarshal(buf, &certs); err != nil {
+		return errors.Wrap(err, `failed to unmarshal certificate chain`)
+	}
+	chain := make(CertificateChain, len(certs))
+	for i, encoded := range certs {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return errors.Wrapf(err, `failed to decode certificate #%d`, i)
+		}
+		if chain[i], err = x509.ParseCertificate(der); err != nil {
+			return errors.Wrapf(err, `failed to parse certificate #%d`, i)
+		}
+	}
+	*c = chain
+	return nil
+}
+
+// withX509 verifies every signature with the public key of the first
+// certificate of its "x5c" header parameter, once the chain has been
+// verified against `roots` and matched against the "x5t" and "x5t#S256"
+// header parameters. The key is used with the "alg" protected header
+// parameter, which must fit the type of the key.
+func (cfg *verifyConfig) withX509(roots *x509.CertPool) candidatesFunc {
+	return func(sig *Signature) ([]verifyCandidate, error) {
+		v, ok := signatureHeader(sig, X509CertChainKey)
+		if !ok {
+			return nil, errors.Errorf(`missing %s header parameter`, X509CertChainKey)
+		}
+		chain, ok := v.(CertificateChain)
+		if !ok || len(chain) == 0 {
+			return nil, errors.Errorf(`invalid value for %s key: %T`, X509CertChainKey, v)
+		}
+
+		leaf := chain[0]
+		intermediates := x509.NewCertPool()
+		for _, cert := range chain[1:] {
+			intermediates.AddCert(cert)
+		}
+		usages := cfg.x509KeyUsages
+		if usages == nil {
+			usages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+		}
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     usages,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to verify certificate chain`)
+		}
+
+		sha1Sum := sha1.Sum(leaf.Raw)
+		if err := checkCertThumbprint(sig, X509CertThumbprintKey, sha1Sum[:]); err != nil {
+			return nil, err
+		}
+		sha256Sum := sha256.Sum256(leaf.Raw)
+		if err := checkCertThumbprint(sig, X509CertThumbprintS256Key, sha256Sum[:]); err != nil {
+			return nil, err
+		}
+
+		if sig.Protected == nil {
+			return nil, errors.New(`missing protected Headers`)
+		}
+		alg := sig.Protected.GetAlgorithm()
+		if err := checkKeyType(alg, leaf.PublicKey); err != nil {
+			return nil, err
+		}
+		return []verifyCandidate{{alg: alg, key: leaf.PublicKey}}, nil
+	}
+}
+
+// checkCertThumbprint makes sure that the certificate thumbprint header
+// parameter `name`, if present, matches `thumbprint`
+func checkCertThumbprint(sig *Signature, name string, thumbprint []byte) error {
+	v, ok := signatureHeader(sig, name)
+	if !ok {
+		return nil
+	}
+	if b, ok := v.([]byte); !ok || !bytes.Equal(b, thumbprint) {
+		return errors.Errorf(`%s header parameter does not match the certificate`, name)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package jws_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/repenno/jwx-opa/jwa"
+	"github.com/repenno/jwx-opa/jws"
+)
+
+// generateCertificate creates a certificate for a new ECDSA key, signed by
+// `parent` using `parentKey`, or self-signed when `parent` is nil
+func generateCertificate(t *testing.T, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err.Error())
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("Failed to generate serial number: %s", err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %s", err.Error())
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %s", err.Error())
+	}
+	return cert, key
+}
+
+func TestX509(t *testing.T) {
+	payload := []byte("Lorem ipsum")
+
+	root, rootKey := generateCertificate(t, "Root CA", true, nil, nil)
+	intermediate, intermediateKey := generateCertificate(t, "Intermediate CA", true, root, rootKey)
+	leaf, leafKey := generateCertificate(t, "Signer", false, intermediate, intermediateKey)
+	otherRoot, _ := generateCertificate(t, "Other Root CA", true, nil, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	sha1Sum := sha1.Sum(leaf.Raw)
+	sha256Sum := sha256.Sum256(leaf.Raw)
+
+	sign := func(t *testing.T, values map[string]interface{}) []byte {
+		t.Helper()
+		var hdr jws.StandardHeaders
+		hdr.Set(jws.AlgorithmKey, jwa.ES256)
+		for k, v := range values {
+			if err := hdr.Set(k, v); err != nil {
+				t.Fatalf("Set failed for %s: %s", k, err.Error())
+			}
+		}
+		hdrBuf, err := json.Marshal(hdr)
+		if err != nil {
+			t.Fatalf("Failed to marshal Headers: %s", err.Error())
+		}
+		signed, err := jws.SignLiteral(payload, jwa.ES256, leafKey, hdrBuf)
+		if err != nil {
+			t.Fatalf("Failed to sign payload: %s", err.Error())
+		}
+		return signed
+	}
+	chain := jws.CertificateChain{leaf, intermediate}
+
+	t.Run("Parse", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{jws.X509CertChainKey: chain, jws.X509URLKey: "https://example.com/chain.pem"})
+		msg, err := jws.ParseByte(signed)
+		if err != nil {
+			t.Fatalf("Failed to parse message: %s", err.Error())
+		}
+		v, ok := msg.GetSignatures()[0].ProtectedHeaders().Get(jws.X509CertChainKey)
+		if !ok {
+			t.Fatal("Missing certificate chain")
+		}
+		parsed := v.(jws.CertificateChain)
+		if len(parsed) != 2 || !parsed[0].Equal(leaf) || !parsed[1].Equal(intermediate) {
+			t.Fatal("Mismatched certificate chain")
+		}
+	})
+	t.Run("Valid chain", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{
+			jws.X509CertChainKey:          chain,
+			jws.X509CertThumbprintKey:     sha1Sum[:],
+			jws.X509CertThumbprintS256Key: sha256Sum[:],
+		})
+		verified, err := jws.VerifyWithX509(signed, roots)
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		if string(verified) != string(payload) {
+			t.Fatal("Mismatched payload")
+		}
+		msg, err := jws.VerifyMessageWithX509(signed, roots)
+		if err != nil {
+			t.Fatalf("Verification failed: %s", err.Error())
+		}
+		verifiedKey, ok := msg.VerifiedKey().(*ecdsa.PublicKey)
+		if !ok || verifiedKey.X.Cmp(leafKey.X) != 0 || verifiedKey.Y.Cmp(leafKey.Y) != 0 {
+			t.Fatal("Expected the message to be verified with the key of the leaf certificate")
+		}
+		if _, ok := msg.GetSignatures()[0].ProtectedHeaders().Get(jws.X509CertChainKey); !ok {
+			t.Fatal("Expected the Headers of the verified signature")
+		}
+	})
+	t.Run("Untrusted root", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{jws.X509CertChainKey: chain})
+		others := x509.NewCertPool()
+		others.AddCert(otherRoot)
+		if _, err := jws.VerifyWithX509(signed, others); err == nil {
+			t.Fatal("Verification with an untrusted root should fail")
+		}
+	})
+	t.Run("Missing intermediate", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{jws.X509CertChainKey: jws.CertificateChain{leaf}})
+		if _, err := jws.VerifyWithX509(signed, roots); err == nil {
+			t.Fatal("Verification of an incomplete chain should fail")
+		}
+	})
+	t.Run("Mismatched thumbprint", func(t *testing.T) {
+		otherSum := sha256.Sum256(intermediate.Raw)
+		signed := sign(t, map[string]interface{}{
+			jws.X509CertChainKey:          chain,
+			jws.X509CertThumbprintS256Key: otherSum[:],
+		})
+		if _, err := jws.VerifyWithX509(signed, roots); err == nil {
+			t.Fatal("Verification with a mismatched thumbprint should fail")
+		}
+	})
+	t.Run("Wrong signer", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{jws.X509CertChainKey: jws.CertificateChain{intermediate}})
+		if _, err := jws.VerifyWithX509(signed, roots); err == nil {
+			t.Fatal("Verification with the key of another certificate should fail")
+		}
+	})
+	t.Run("Missing chain", func(t *testing.T) {
+		signed := sign(t, nil)
+		if _, err := jws.VerifyWithX509(signed, roots); err == nil {
+			t.Fatal("Verification without a certificate chain should fail")
+		}
+	})
+	t.Run("Key usage", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{jws.X509CertChainKey: chain})
+		if _, err := jws.VerifyWithX509(signed, roots, jws.WithX509KeyUsages(x509.ExtKeyUsageCodeSigning)); err == nil {
+			t.Fatal("Verification of a certificate without the required usage should fail")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package mldsa
+
+// packBits appends the low `bits` bits of each of `values` to `out`, least
+// significant bit first, as described in Algorithms 16 and 17 of FIPS 204
+func packBits(out []byte, values *[n]uint32, bits uint) []byte {
+	var acc uint64
+	var accBits uint
+	for _, v := range values {
+		acc |= uint64(v) << accBits
+		accBits += bits
+		for accBits >= 8 {
+			out = append(out, byte(acc))
+			acc >>= 8
+			accBits -= 8
+		}
+	}
+	return out
+}
+
+// unpackBits is the inverse of packBits, reading n·bits/8 bytes of `in`
+func unpackBits(in []byte, bits uint) [n]uint32 {
+	var values [n]uint32
+	var acc uint64
+	var accBits uint
+	mask := uint64(1)<<bits - 1
+	for i := range values {
+		for accBits < bits {
+			acc |= uint64(in[0]) << accBits
+			in = in[1:]
+			accBits += 8
+		}
+		values[i] = uint32(acc & mask)
+		acc >>= bits
+		accBits -= bits
+	}
+	return values
+}
+
+// bitLen returns the number of bits needed to represent `v`
+func bitLen(v uint32) uint {
+	var bits uint
+	for ; v > 0; v >>= 1 {
+		bits++
+	}
+	return bits
+}
+
+// simpleBitPack encodes `f`, whose coefficients are in [0, b]
+func simpleBitPack(out []byte, f *[n]uint32, b uint32) []byte {
+	return packBits(out, f, bitLen(b))
+}
+
+// bitPack encodes `f`, whose coefficients are in [-a, b], as b - f
+func bitPack(out []byte, f *ringElement, a, b uint32) []byte {
+	var values [n]uint32
+	for i, v := range f {
+		values[i] = uint32(fieldSub(fieldElement(b), v))
+	}
+	return packBits(out, &values, bitLen(a+b))
+}
+
+// bitUnpack is the inverse of bitPack
+func bitUnpack(in []byte, a, b uint32) ringElement {
+	var f ringElement
+	values := unpackBits(in, bitLen(a+b))
+	for i, v := range values {
+		f[i] = fieldSub(fieldElement(b), fieldReduce(v))
+	}
+	return f
+}
+
+// hintBitPack encodes the positions of the hints of `h`, as described in
+// Algorithm 20 of FIPS 204
+func hintBitPack(out []byte, h [][n]bool, omega int) []byte {
+	y := make([]byte, omega+len(h))
+	var index int
+	for i := range h {
+		for j, hint := range h[i] {
+			if hint {
+				y[index] = byte(j)
+				index++
+			}
+		}
+		y[omega+i] = byte(index)
+	}
+	return append(out, y...)
+}
+
+// hintBitUnpack is the inverse of hintBitPack, which rejects the encodings
+// that are not canonical, as described in Algorithm 21 of FIPS 204
+func hintBitUnpack(y []byte, k, omega int) ([][n]bool, bool) {
+	h := make([][n]bool, k)
+	var index int
+	for i := 0; i < k; i++ {
+		end := int(y[omega+i])
+		if end < index || end > omega {
+			return nil, false
+		}
+		first := index
+		for ; index < end; index++ {
+			if index > first && y[index-1] >= y[index] {
+				return nil, false
+			}
+			h[i][y[index]] = true
+		}
+	}
+	for ; index < omega; index++ {
+		if y[index] != 0 {
+			return nil, false
+		}
+	}
+	return h, true
+}
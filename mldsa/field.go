@@ -0,0 +1,207 @@
+package mldsa
+
+const (
+	q = 8380417 // modulus of the field Zq
+	n = 256     // number of coefficients of the polynomials
+	d = 13      // number of dropped bits from t
+
+	// invN is 256⁻¹ mod q, which scales the result of the inverse NTT
+	invN = 8347681
+	// zeta is the 512th root of unity mod q used by the NTT
+	zeta = 1753
+)
+
+// fieldElement is an element of Zq, in the range [0, q)
+type fieldElement uint32
+
+// ringElement is a polynomial of Rq, either in the standard or in the NTT
+// domain
+type ringElement [n]fieldElement
+
+func fieldAdd(a, b fieldElement) fieldElement {
+	return fieldReduce(uint32(a) + uint32(b))
+}
+
+func fieldSub(a, b fieldElement) fieldElement {
+	return fieldReduce(uint32(a) + q - uint32(b))
+}
+
+func fieldMul(a, b fieldElement) fieldElement {
+	return fieldElement(uint64(a) * uint64(b) % q)
+}
+
+// fieldReduce reduces `a`, which is less than 2q
+func fieldReduce(a uint32) fieldElement {
+	if a >= q {
+		a -= q
+	}
+	return fieldElement(a)
+}
+
+// fieldFromInt returns the element congruent to `a`, which is in (-q, q)
+func fieldFromInt(a int32) fieldElement {
+	if a < 0 {
+		a += q
+	}
+	return fieldElement(a)
+}
+
+// centered returns a mod± q, in the range [-(q-1)/2, (q-1)/2]
+func (a fieldElement) centered() int32 {
+	if a > (q-1)/2 {
+		return int32(a) - q
+	}
+	return int32(a)
+}
+
+// infinityNorm returns |a mod± q|
+func (a fieldElement) infinityNorm() uint32 {
+	if a > (q-1)/2 {
+		return q - uint32(a)
+	}
+	return uint32(a)
+}
+
+// infinityNorm returns the largest infinityNorm of the coefficients of `f`
+func (f *ringElement) infinityNorm() uint32 {
+	var max uint32
+	for _, a := range f {
+		if v := a.infinityNorm(); v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// zetas holds zeta^BitRev8(k) mod q for k in [0, 256)
+var zetas = func() [n]fieldElement {
+	var powers, out [n]fieldElement
+	powers[0] = 1
+	for i := 1; i < n; i++ {
+		powers[i] = fieldMul(powers[i-1], zeta)
+	}
+	for k := 0; k < n; k++ {
+		var rev int
+		for bit := 0; bit < 8; bit++ {
+			rev |= (k >> uint(bit) & 1) << uint(7-bit)
+		}
+		out[k] = powers[rev]
+	}
+	return out
+}()
+
+// ntt computes the number-theoretic transform of `f` in place, as
+// described in Algorithm 41 of FIPS 204
+func ntt(f *ringElement) {
+	m := 0
+	for length := 128; length >= 1; length /= 2 {
+		for start := 0; start < n; start += 2 * length {
+			m++
+			z := zetas[m]
+			for j := start; j < start+length; j++ {
+				t := fieldMul(z, f[j+length])
+				f[j+length] = fieldSub(f[j], t)
+				f[j] = fieldAdd(f[j], t)
+			}
+		}
+	}
+}
+
+// invNTT computes the inverse of ntt in place, as described in Algorithm
+// 42 of FIPS 204
+func invNTT(f *ringElement) {
+	m := n
+	for length := 1; length < n; length *= 2 {
+		for start := 0; start < n; start += 2 * length {
+			m--
+			z := q - zetas[m]
+			for j := start; j < start+length; j++ {
+				t := f[j]
+				f[j] = fieldAdd(t, f[j+length])
+				f[j+length] = fieldMul(z, fieldSub(t, f[j+length]))
+			}
+		}
+	}
+	for j := range f {
+		f[j] = fieldMul(f[j], invN)
+	}
+}
+
+// nttMul returns the product of `f` and `g`, in the NTT domain
+func nttMul(f, g *ringElement) ringElement {
+	var h ringElement
+	for i := range h {
+		h[i] = fieldMul(f[i], g[i])
+	}
+	return h
+}
+
+func polyAdd(f, g *ringElement) ringElement {
+	var h ringElement
+	for i := range h {
+		h[i] = fieldAdd(f[i], g[i])
+	}
+	return h
+}
+
+func polySub(f, g *ringElement) ringElement {
+	var h ringElement
+	for i := range h {
+		h[i] = fieldSub(f[i], g[i])
+	}
+	return h
+}
+
+// power2Round splits `r` into r1·2^d + r0, as described in Algorithm 35 of
+// FIPS 204
+func power2Round(r fieldElement) (fieldElement, fieldElement) {
+	r0 := int32(r & (1<<d - 1))
+	if r0 > 1<<(d-1) {
+		r0 -= 1 << d
+	}
+	return fieldElement((int32(r) - r0) >> d), fieldFromInt(r0)
+}
+
+// decompose splits `r` into its high and low bits, as described in
+// Algorithm 36 of FIPS 204
+func decompose(r fieldElement, gamma2 uint32) (uint32, int32) {
+	r0 := int32(uint32(r) % (2 * gamma2))
+	if r0 > int32(gamma2) {
+		r0 -= int32(2 * gamma2)
+	}
+	if int32(r)-r0 == q-1 {
+		return 0, r0 - 1
+	}
+	return uint32(int32(r)-r0) / (2 * gamma2), r0
+}
+
+func highBits(r fieldElement, gamma2 uint32) uint32 {
+	r1, _ := decompose(r, gamma2)
+	return r1
+}
+
+func lowBits(r fieldElement, gamma2 uint32) int32 {
+	_, r0 := decompose(r, gamma2)
+	return r0
+}
+
+// makeHint reports whether adding `z` to `r` alters its high bits, as
+// described in Algorithm 39 of FIPS 204
+func makeHint(z, r fieldElement, gamma2 uint32) bool {
+	return highBits(r, gamma2) != highBits(fieldAdd(r, z), gamma2)
+}
+
+// useHint returns the high bits of `r` adjusted according to the hint
+// `h`, as described in Algorithm 40 of FIPS 204
+func useHint(h bool, r fieldElement, gamma2 uint32) uint32 {
+	m := (q - 1) / (2 * gamma2)
+	r1, r0 := decompose(r, gamma2)
+	switch {
+	case h && r0 > 0:
+		return (r1 + 1) % m
+	case h:
+		return (r1 + m - 1) % m
+	default:
+		return r1
+	}
+}
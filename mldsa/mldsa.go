@@ -0,0 +1,446 @@
+// Package mldsa implements the ML-DSA post-quantum signature scheme, as
+// described in https://nvlpubs.nist.gov/nistpubs/FIPS/NIST.FIPS.204.pdf
+//
+// The implementation is written in plain Go for portability, and follows
+// the algorithms of the specification. It is not hardened against side
+// channel attacks.
+package mldsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/subtle"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// SeedSize is the size of the seed from which a private key is derived
+const SeedSize = 32
+
+// Parameters is one of the parameter sets of ML-DSA
+type Parameters struct {
+	name   string
+	k, l   int
+	eta    int
+	tau    int
+	lambda int // collision strength of the commitment hash, in bits
+	gamma1 uint32
+	gamma2 uint32
+	omega  int
+}
+
+var (
+	mldsa44 = &Parameters{name: "ML-DSA-44", k: 4, l: 4, eta: 2, tau: 39, lambda: 128, gamma1: 1 << 17, gamma2: (q - 1) / 88, omega: 80}
+	mldsa65 = &Parameters{name: "ML-DSA-65", k: 6, l: 5, eta: 4, tau: 49, lambda: 192, gamma1: 1 << 19, gamma2: (q - 1) / 32, omega: 55}
+	mldsa87 = &Parameters{name: "ML-DSA-87", k: 8, l: 7, eta: 2, tau: 60, lambda: 256, gamma1: 1 << 19, gamma2: (q - 1) / 32, omega: 75}
+)
+
+// MLDSA44 returns the ML-DSA-44 parameter set
+func MLDSA44() *Parameters { return mldsa44 }
+
+// MLDSA65 returns the ML-DSA-65 parameter set
+func MLDSA65() *Parameters { return mldsa65 }
+
+// MLDSA87 returns the ML-DSA-87 parameter set
+func MLDSA87() *Parameters { return mldsa87 }
+
+// Name returns the name of the parameter set, such as "ML-DSA-44"
+func (p *Parameters) Name() string {
+	return p.name
+}
+
+// PublicKeySize returns the size in bytes of the encoded public keys
+func (p *Parameters) PublicKeySize() int {
+	return 32 + 32*p.k*10
+}
+
+// SignatureSize returns the size in bytes of the signatures
+func (p *Parameters) SignatureSize() int {
+	return p.lambda/4 + 32*p.l*int(1+bitLen(p.gamma1-1)) + p.omega + p.k
+}
+
+// beta is the bound τ·η of the coefficients of c·s1 and c·s2
+func (p *Parameters) beta() uint32 {
+	return uint32(p.tau * p.eta)
+}
+
+// w1Bits is the size of the coefficients of the encoding of w1
+func (p *Parameters) w1Bits() uint {
+	return bitLen((q-1)/(2*p.gamma2) - 1)
+}
+
+// PublicKey is an ML-DSA public key
+type PublicKey struct {
+	params  *Parameters
+	encoded []byte
+	rho     []byte
+	t1      [][n]uint32
+	tr      []byte // hash of the encoded public key
+}
+
+// PrivateKey is an ML-DSA private key, which is derived from a seed
+type PrivateKey struct {
+	pub  *PublicKey
+	seed []byte
+	key  []byte
+	s1   []ringElement
+	s2   []ringElement
+	t0   []ringElement
+}
+
+// Options holds the options of signing and verifying ML-DSA signatures.
+// It can be given to PrivateKey.Sign as crypto.SignerOpts.
+type Options struct {
+	// Context is the context string of at most 255 bytes, which must be
+	// the same when signing and verifying
+	Context []byte
+}
+
+// HashFunc returns 0, as ML-DSA signs messages that are not pre-hashed
+func (o *Options) HashFunc() crypto.Hash {
+	return crypto.Hash(0)
+}
+
+// GenerateKey generates a private key using the seed read from `rand`
+func GenerateKey(params *Parameters, rand io.Reader) (*PrivateKey, error) {
+	seed := make([]byte, SeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, errors.Wrap(err, `failed to read seed`)
+	}
+	return NewPrivateKey(params, seed)
+}
+
+// NewPrivateKey derives the private key of the parameter set `params` from
+// `seed`, as described in Algorithm 6 of FIPS 204
+func NewPrivateKey(params *Parameters, seed []byte) (*PrivateKey, error) {
+	if params == nil {
+		return nil, errors.New(`missing parameter set`)
+	}
+	if len(seed) != SeedSize {
+		return nil, errors.Errorf(`invalid seed size %d`, len(seed))
+	}
+	k, l := params.k, params.l
+
+	expanded := shake256(128, seed, []byte{byte(k), byte(l)})
+	rho, rhoPrime, key := expanded[:32], expanded[32:96], expanded[96:]
+
+	a := expandA(rho, k, l)
+	s1, s2 := expandS(rhoPrime, k, l, params.eta)
+	s1Hat := nttVector(s1)
+
+	t1 := make([][n]uint32, k)
+	t0 := make([]ringElement, k)
+	for i := 0; i < k; i++ {
+		t := matrixRow(a[i*l:(i+1)*l], s1Hat)
+		t = polyAdd(&t, &s2[i])
+		for c := range t {
+			r1, r0 := power2Round(t[c])
+			t1[i][c] = uint32(r1)
+			t0[i][c] = r0
+		}
+	}
+
+	pub := newPublicKey(params, rho, t1)
+	sk := &PrivateKey{
+		pub:  pub,
+		seed: append([]byte(nil), seed...),
+		key:  key,
+		s1:   s1,
+		s2:   s2,
+		t0:   t0,
+	}
+	return sk, nil
+}
+
+// newPublicKey encodes the public key made of `rho` and `t1`, as described
+// in Algorithm 22 of FIPS 204
+func newPublicKey(params *Parameters, rho []byte, t1 [][n]uint32) *PublicKey {
+	encoded := make([]byte, 0, params.PublicKeySize())
+	encoded = append(encoded, rho...)
+	for i := range t1 {
+		encoded = simpleBitPack(encoded, &t1[i], 1<<10-1)
+	}
+	return &PublicKey{
+		params:  params,
+		encoded: encoded,
+		rho:     encoded[:32],
+		t1:      t1,
+		tr:      shake256(64, encoded),
+	}
+}
+
+// NewPublicKey decodes the public key of the parameter set `params`, as
+// described in Algorithm 23 of FIPS 204
+func NewPublicKey(params *Parameters, encoded []byte) (*PublicKey, error) {
+	if params == nil {
+		return nil, errors.New(`missing parameter set`)
+	}
+	if len(encoded) != params.PublicKeySize() {
+		return nil, errors.Errorf(`invalid public key size %d`, len(encoded))
+	}
+	encoded = append([]byte(nil), encoded...)
+	t1 := make([][n]uint32, params.k)
+	for i := range t1 {
+		t1[i] = unpackBits(encoded[32+320*i:], 10)
+	}
+	return newPublicKey(params, encoded[:32], t1), nil
+}
+
+// Parameters returns the parameter set of the key
+func (pk *PublicKey) Parameters() *Parameters {
+	return pk.params
+}
+
+// Bytes returns the encoding of the public key
+func (pk *PublicKey) Bytes() []byte {
+	return append([]byte(nil), pk.encoded...)
+}
+
+// Equal reports whether `x` is the same public key
+func (pk *PublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(*PublicKey)
+	return ok && pk.params == other.params && bytes.Equal(pk.encoded, other.encoded)
+}
+
+// Parameters returns the parameter set of the key
+func (sk *PrivateKey) Parameters() *Parameters {
+	return sk.pub.params
+}
+
+// Seed returns the seed from which the private key is derived
+func (sk *PrivateKey) Seed() []byte {
+	return append([]byte(nil), sk.seed...)
+}
+
+// PublicKey returns the public key matching the private key
+func (sk *PrivateKey) PublicKey() *PublicKey {
+	return sk.pub
+}
+
+// Public returns the public key matching the private key, as required by
+// crypto.Signer
+func (sk *PrivateKey) Public() crypto.PublicKey {
+	return sk.pub
+}
+
+// Equal reports whether `x` is the same private key
+func (sk *PrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*PrivateKey)
+	return ok && sk.pub.params == other.pub.params && subtle.ConstantTimeCompare(sk.seed, other.seed) == 1
+}
+
+// Sign signs `message`, which is not pre-hashed. The context string is
+// taken from `opts` when it is an *Options. When `rand` is nil the
+// deterministic variant of ML-DSA is used, and otherwise the hedged one
+// with randomness read from `rand`.
+func (sk *PrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New(`ML-DSA does not sign pre-hashed messages`)
+	}
+	var context []byte
+	if o, ok := opts.(*Options); ok {
+		context = o.Context
+	}
+	formatted, err := formatMessage(context, message)
+	if err != nil {
+		return nil, err
+	}
+	rnd := make([]byte, 32)
+	if rand != nil {
+		if _, err := io.ReadFull(rand, rnd); err != nil {
+			return nil, errors.Wrap(err, `failed to read randomness`)
+		}
+	}
+	return sk.signInternal(formatted, rnd), nil
+}
+
+// Verify reports whether `sig` is a valid signature of `message` by `pk`,
+// using the context string of `opts` if not nil
+func Verify(pk *PublicKey, message, sig []byte, opts *Options) bool {
+	var context []byte
+	if opts != nil {
+		context = opts.Context
+	}
+	formatted, err := formatMessage(context, message)
+	if err != nil {
+		return false
+	}
+	return pk.verifyInternal(formatted, sig)
+}
+
+// formatMessage prefixes `message` with the domain separator of pure
+// ML-DSA and `context`, as described in Algorithm 2 of FIPS 204
+func formatMessage(context, message []byte) ([]byte, error) {
+	if len(context) > 255 {
+		return nil, errors.Errorf(`context string of %d bytes is too long`, len(context))
+	}
+	formatted := make([]byte, 0, 2+len(context)+len(message))
+	formatted = append(formatted, 0, byte(len(context)))
+	formatted = append(formatted, context...)
+	return append(formatted, message...), nil
+}
+
+// signInternal signs the formatted message `m` using the randomness
+// `rnd`, as described in Algorithm 7 of FIPS 204
+func (sk *PrivateKey) signInternal(m, rnd []byte) []byte {
+	params := sk.pub.params
+	k, l := params.k, params.l
+	beta := params.beta()
+
+	a := expandA(sk.pub.rho, k, l)
+	s1Hat := nttVector(sk.s1)
+	s2Hat := nttVector(sk.s2)
+	t0Hat := nttVector(sk.t0)
+
+	mu := shake256(64, sk.pub.tr, m)
+	rhoPrime := shake256(64, sk.key, rnd, mu)
+
+	for kappa := 0; ; kappa += l {
+		y := expandMask(rhoPrime, kappa, l, params.gamma1)
+		yHat := nttVector(y)
+		w := make([]ringElement, k)
+		w1 := make([][n]uint32, k)
+		for i := 0; i < k; i++ {
+			w[i] = matrixRow(a[i*l:(i+1)*l], yHat)
+			for c := range w[i] {
+				w1[i][c] = highBits(w[i][c], params.gamma2)
+			}
+		}
+
+		cTilde := commitmentHash(params, mu, w1)
+		cHat := sampleInBall(cTilde, params.tau)
+		ntt(&cHat)
+
+		z := make([]ringElement, l)
+		valid := true
+		for j := 0; j < l && valid; j++ {
+			cs1 := nttMul(&cHat, &s1Hat[j])
+			invNTT(&cs1)
+			z[j] = polyAdd(&y[j], &cs1)
+			valid = z[j].infinityNorm() < params.gamma1-beta
+		}
+		if !valid {
+			continue
+		}
+
+		h := make([][n]bool, k)
+		var hints int
+		for i := 0; i < k && valid; i++ {
+			cs2 := nttMul(&cHat, &s2Hat[i])
+			invNTT(&cs2)
+			r := polySub(&w[i], &cs2)
+			ct0 := nttMul(&cHat, &t0Hat[i])
+			invNTT(&ct0)
+			if ct0.infinityNorm() >= params.gamma2 {
+				valid = false
+				break
+			}
+			for c := range r {
+				r0 := lowBits(r[c], params.gamma2)
+				if r0 >= int32(params.gamma2-beta) || -r0 >= int32(params.gamma2-beta) {
+					valid = false
+					break
+				}
+				if makeHint(q-ct0[c], fieldAdd(r[c], ct0[c]), params.gamma2) {
+					h[i][c] = true
+					hints++
+				}
+			}
+		}
+		if !valid || hints > params.omega {
+			continue
+		}
+
+		sig := make([]byte, 0, params.SignatureSize())
+		sig = append(sig, cTilde...)
+		for j := range z {
+			sig = bitPack(sig, &z[j], params.gamma1-1, params.gamma1)
+		}
+		return hintBitPack(sig, h, params.omega)
+	}
+}
+
+// verifyInternal verifies the signature `sig` of the formatted message
+// `m`, as described in Algorithm 8 of FIPS 204
+func (pk *PublicKey) verifyInternal(m, sig []byte) bool {
+	params := pk.params
+	k, l := params.k, params.l
+	if len(sig) != params.SignatureSize() {
+		return false
+	}
+
+	cTilde := sig[:params.lambda/4]
+	zBytes := 32 * int(1+bitLen(params.gamma1-1))
+	z := make([]ringElement, l)
+	for j := range z {
+		z[j] = bitUnpack(sig[len(cTilde)+zBytes*j:], params.gamma1-1, params.gamma1)
+		if z[j].infinityNorm() >= params.gamma1-params.beta() {
+			return false
+		}
+	}
+	h, ok := hintBitUnpack(sig[len(cTilde)+zBytes*l:], k, params.omega)
+	if !ok {
+		return false
+	}
+
+	a := expandA(pk.rho, k, l)
+	mu := shake256(64, pk.tr, m)
+	cHat := sampleInBall(cTilde, params.tau)
+	ntt(&cHat)
+	zHat := nttVector(z)
+
+	w1 := make([][n]uint32, k)
+	for i := 0; i < k; i++ {
+		var t1 ringElement
+		for c, v := range pk.t1[i] {
+			t1[c] = fieldElement(v << d)
+		}
+		ntt(&t1)
+		ct1 := nttMul(&cHat, &t1)
+		az := matrixRowNTT(a[i*l:(i+1)*l], zHat)
+		w := polySub(&az, &ct1)
+		invNTT(&w)
+		for c := range w {
+			w1[i][c] = useHint(h[i][c], w[c], params.gamma2)
+		}
+	}
+	return subtle.ConstantTimeCompare(cTilde, commitmentHash(params, mu, w1)) == 1
+}
+
+// commitmentHash hashes `mu` and the encoding of `w1`
+func commitmentHash(params *Parameters, mu []byte, w1 [][n]uint32) []byte {
+	var encoded []byte
+	for i := range w1 {
+		encoded = packBits(encoded, &w1[i], params.w1Bits())
+	}
+	return shake256(params.lambda/4, mu, encoded)
+}
+
+// nttVector returns the NTT of each polynomial of `v`
+func nttVector(v []ringElement) []ringElement {
+	out := make([]ringElement, len(v))
+	for i := range v {
+		out[i] = v[i]
+		ntt(&out[i])
+	}
+	return out
+}
+
+// matrixRowNTT returns the dot product of `row` and `v`, in the NTT domain
+func matrixRowNTT(row, v []ringElement) ringElement {
+	var sum ringElement
+	for j := range row {
+		product := nttMul(&row[j], &v[j])
+		sum = polyAdd(&sum, &product)
+	}
+	return sum
+}
+
+// matrixRow returns the dot product of `row` and `v` in the standard
+// domain, where both are given in the NTT domain
+func matrixRow(row, v []ringElement) ringElement {
+	sum := matrixRowNTT(row, v)
+	invNTT(&sum)
+	return sum
+}
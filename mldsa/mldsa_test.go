@@ -0,0 +1,404 @@
+package mldsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func fromHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Failed to decode %s: %s", s, err.Error())
+	}
+	return b
+}
+
+// encodePrivateKey encodes `sk` in the expanded format of Algorithm 24 of
+// FIPS 204, which the test vectors use
+func encodePrivateKey(sk *PrivateKey) []byte {
+	params := sk.pub.params
+	out := append([]byte(nil), sk.pub.rho...)
+	out = append(out, sk.key...)
+	out = append(out, sk.pub.tr...)
+	for _, s := range append(append([]ringElement(nil), sk.s1...), sk.s2...) {
+		s := s
+		out = bitPack(out, &s, uint32(params.eta), uint32(params.eta))
+	}
+	for i := range sk.t0 {
+		out = bitPack(out, &sk.t0[i], 1<<(d-1)-1, 1<<(d-1))
+	}
+	return out
+}
+
+func TestShake(t *testing.T) {
+	t.Run("SHAKE128", func(t *testing.T) {
+		out := make([]byte, 32)
+		newShake128().squeeze(out)
+		if hex.EncodeToString(out) != "7f9c2ba4e88f827d616045507605853ed73b8093f6efbc88eb1a6eacfa66ef26" {
+			t.Fatalf("Unexpected output %x", out)
+		}
+	})
+	t.Run("SHAKE256", func(t *testing.T) {
+		out := shake256(32)
+		if hex.EncodeToString(out) != "46b9dd2b0ba88d13233b3feb743eeb243fcd52ea62b81b82b50c27646ed5762f" {
+			t.Fatalf("Unexpected output %x", out)
+		}
+	})
+	t.Run("Incremental", func(t *testing.T) {
+		input := bytes.Repeat([]byte("Lorem ipsum"), 50)
+		want := shake256(500, input)
+		s := newShake256()
+		s.absorb(input[:100], input[100:101], input[101:])
+		got := make([]byte, 500)
+		s.squeeze(got[:1])
+		s.squeeze(got[1:300])
+		s.squeeze(got[300:])
+		if !bytes.Equal(got, want) {
+			t.Fatal("Incremental output does not match")
+		}
+	})
+}
+
+// TestKnownAnswers uses the rejection test vectors of
+// https://pages.nist.gov/ACVP/draft-celi-acvp-ml-dsa.html, whose messages
+// are the input of ML-DSA.Sign_internal
+func TestKnownAnswers(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *Parameters
+		seed    string
+		keyHash string // SHA-256 of the public key and the expanded private key
+		message string
+		sigHash string // SHA-256 of the deterministic signature
+	}{
+		{
+			name:    "Path/ML-DSA-44/1",
+			params:  MLDSA44(),
+			seed:    "5c624fcc1862452452d0c665840d8237f43108e5499edcdc108fbc49d596e4b7",
+			keyHash: "ac825c59d8a4c453a2c4efea8395741ca404f3000e28d56b25d03bb402e5cb2f",
+			message: "951fdf5473a4cba6d9e5b5db7e79fb8173921ba5b13e9271401b8f907b8b7d5b",
+			sigHash: "dcc71a421bc6ffafb7df0c7f6d018a19ada154d1e2ee360ed533cecd5dc980ad",
+		},
+		{
+			name:    "Path/ML-DSA-44/2",
+			params:  MLDSA44(),
+			seed:    "836eabedb4d2cd9be6a4d957cf5ee6bf489304136864c55c2c5f01da5047d18b",
+			keyHash: "e1ff40d96e3552fab531d1715084b7e38ccdbacc0a8af94c30959fb4c7f5a445",
+			message: "199a0ab735e9004163dd02d319a61cfe81638e3bf47bb1e90e90d6e3ea545247",
+			sigHash: "a2608bc27e60541d27b6a14f460d54a48c0298dcc3f45999f29047a3135c4941",
+		},
+		{
+			name:    "Path/ML-DSA-44/3",
+			params:  MLDSA44(),
+			seed:    "ca5a01e1ea6552cb5c9803462b94c2f1dc9d13bb17a6ace510d157056a2c6114",
+			keyHash: "a4652dc4a271095268dd84a5b0744dfdbe2e642e4d41fbc4329c2fba534c0e13",
+			message: "8c8caca88fff52b9330510537b3701b3993f3726136a650f48f8604551550832",
+			sigHash: "b4b142209137397dad504caed01d390adaf49973d8d2414fc3457fb7af775189",
+		},
+		{
+			name:    "Path/ML-DSA-44/4",
+			params:  MLDSA44(),
+			seed:    "9c005f1550b4f31855c6b92f978736733f37791cb39dd182d7ba5732bdc2483e",
+			keyHash: "2485aa99345f1b334d4d94b610fbffccb626cbfd4e9ff0e1f6fc35093c423544",
+			message: "b744343f30f7fee088998ba574e799f1bf3939c06c29bf9ac10f3588a57e21e2",
+			sigHash: "5b80a60baa480b9d0c7d2c05b50928c4bf6808dda693642058a3eb77eaa768fc",
+		},
+		{
+			name:    "Path/ML-DSA-44/5",
+			params:  MLDSA44(),
+			seed:    "4fab5485b009399e8ae6fc3d3eefbfe8e09796e4477aabd5eb1cc908fa734de3",
+			keyHash: "cb56909a7cf3008a662dc635edcb79dc151ca7acbae17b544384abd91bbbc1e9",
+			message: "7cab0fdcf4bea5f039137478aa45c9c48ef96d906fc49f6e2f138111bf1b4a4e",
+			sigHash: "6cc38d73d639682abc556dc6dcf436de24033091f34004f410fabc6887f77ab0",
+		},
+		{
+			name:    "Path/ML-DSA-65/1",
+			params:  MLDSA65(),
+			seed:    "464756a985e5df03739d95dd309c1ed9c5b04254cc294e7e7eb9b9365ee15117",
+			keyHash: "ae95ea0daa80199e7b4a74eb5a1b1dc6c3805bd01d2fa78d7c4fba8c255aa13d",
+			message: "491101bba044de6e44a63796c33cda051bb05a60725b87af4ba9db940c03ac09",
+			sigHash: "8e08ea0c8db941685b9905a73b0b57bad3500b1f73490480b24375b41230cc04",
+		},
+		{
+			name:    "Path/ML-DSA-65/2",
+			params:  MLDSA65(),
+			seed:    "235a48db4ca7916b884f424a8586efd517e87c64aecec0fce9a3cc212ba1522e",
+			keyHash: "1ac58a909db4d7bc2473ab5e24af768279c76f86a82d448258e24eea4ea6b713",
+			message: "f8ce85cb2ec474ffbf5a3ffae029ce6f4526b8d597655067f97f438b81071e9b",
+			sigHash: "ae9531a01738615b6d33c77b3ff618a86e101fdc4c8504681f0edfa64511ad63",
+		},
+		{
+			name:    "Path/ML-DSA-65/3",
+			params:  MLDSA65(),
+			seed:    "e13131b705a760305feffebfe99082e2691a444bbefcc3edf67d909886200207",
+			keyHash: "b422093f95cc489c52f4fa2b8973a2fddd44426d1d04d1aaeefc8715d417181f",
+			message: "cd365512c7e61bbaa130800b37f3bb46aaf1beef3742ea8a9010a6dd4576ed0b",
+			sigHash: "3c55e604deca7b89a99305d7a391c35f66a17c1923f467675ec951c0948d21c9",
+		},
+		{
+			name:    "Path/ML-DSA-65/4",
+			params:  MLDSA65(),
+			seed:    "0a4793e040a4bc0d0f37643d12c1ea1f10648724609936c76e0ec83e37209e92",
+			keyHash: "622d26d536d4d66cd94956b33a74e2e830ed265d25c34ff7c3e5243403146adf",
+			message: "6d9c7a795e48d80a892cbf4d4558429787277e3806eb5d0bce1640eebbbf9aec",
+			sigHash: "3b141110b9f56540b2d49aacde6399974a4eac40621e367e68d4504f294db21b",
+		},
+		{
+			name:    "Path/ML-DSA-65/5",
+			params:  MLDSA65(),
+			seed:    "f865b889e5022d54babc81ca67e7eb39f1ac42f92cf5295c3da5c9667db1b924",
+			keyHash: "45bc8edd1a620c46e973e346844270721824d97888bc174281852d98b7e8f4a3",
+			message: "047afaadbe020ed2d766da85317dede80be550545f0b21e3f555a990f8004258",
+			sigHash: "56308a3578360c41356ba9c97d3240e01767fa76bbba9fd0cc6cfa9add088db9",
+		},
+		{
+			name:    "Path/ML-DSA-87/1",
+			params:  MLDSA87(),
+			seed:    "0d58219132746be077dfe821e9f8fd87857b28ab91d6a567e312a73e2636032c",
+			keyHash: "4d261270341a7ac6b66900ddc2b8ab34ab483c897410ddf3b2c072bdda416434",
+			message: "3aa49ef72d010aec19383ba1e83ec2dd3dcc207a96ffceb9ffa269e3e3d66400",
+			sigHash: "5049dc39045618b903c71595b3a3e07a731f95d37304623acc98bcef4258b4ca",
+		},
+		{
+			name:    "Path/ML-DSA-87/2",
+			params:  MLDSA87(),
+			seed:    "146c47ab9f88408eb76a813294d533b29d7e0fda75da5a4e7c69eb61efeebb78",
+			keyHash: "05194438af855b79db8ccccb647d6ba5c7aaf901bbd09d3b29395f0ea431d164",
+			message: "82c44f998a8d24f056084d0e80ecfd8434493385a284c69974923c270d397782",
+			sigHash: "cffc5988a351e14a3ee1282f042a143679c4503814296b27993949a7ff966f57",
+		},
+		{
+			name:    "Path/ML-DSA-87/3",
+			params:  MLDSA87(),
+			seed:    "049d9b0b646a2ac7f50b63ce5e4bfe44c9b87634f4ff6c14c513e388b8a1f808",
+			keyHash: "ac8fe6b2fe26591b129ea536a9a001c785d8acbdd9489f6e51469a156e9e635d",
+			message: "febc9f8ae159002be1a11d395959dd7fc20718135690cdaa2bcfb5801c02ab89",
+			sigHash: "ff4006089bdf7337e868f86ddf48f239d2a52ea1d0f686e0103bf19c3b571db1",
+		},
+		{
+			name:    "Path/ML-DSA-87/4",
+			params:  MLDSA87(),
+			seed:    "9823ddde446a8ea883dad3ac6477f79839fdc2d2def2416be0a8b71cfbc3f5c6",
+			keyHash: "525010e307c4ea7667d54ee27007c219b01f4cf88dc3ab2de8e9aaa59440a884",
+			message: "f7592c97c1a96a2f4053588f5cdad4c50bf7c3752709854fa27779b445dd2ba2",
+			sigHash: "fd7757602b83b0a67a314cd5bcc880e7ae47acdf4d6af98269028efb486838f7",
+		},
+		{
+			name:    "Path/ML-DSA-87/5",
+			params:  MLDSA87(),
+			seed:    "ae213fe8589b414f53780d8b9b6837179967e13cb474c5ad365c043778d2bc90",
+			keyHash: "d4988e91064e5df6d867434d1ded16dcd8533e39e420dc2b4eb9e40a84146f7d",
+			message: "19c1913ba76ff04596bb7cc80fd825a5aedef5d5ad61cedb5203e6d7edb18877",
+			sigHash: "23fe743edd101970d499e7eb57a7aa245baf417e851b260c55dd525a445f08da",
+		},
+		{
+			name:    "Count/ML-DSA-44/77",
+			params:  MLDSA44(),
+			seed:    "090d97c1f4166eb32ca67c5fb564acbe0735db4af4b8db3a7c2ce7402357ca44",
+			keyHash: "26d79e4068040e996bc9eb5034c20489c0ad38dc2fec1918d0760c8621872408",
+			message: "e3838364b37f47edfca2b577b20b80c3cb51b9f56e0e4cdb7df002c874039252",
+			sigHash: "cd91150c610ff02de1dd7049c309efe800ce5c1bc2e5a32d752ab62c5bf5e16f",
+		},
+		{
+			name:    "Count/ML-DSA-44/100",
+			params:  MLDSA44(),
+			seed:    "cfc73d07a883543a804f770070861825143a62f2f97d05fce00fd8b25d29a43f",
+			keyHash: "89142ab26d6eb6c01fa3f189a9c877597740d685983f29bbdd3596648266ae0e",
+			message: "0960c13e9ba467a938450120cc96ff6f04b7e557c99a838619a48f9a38738ab8",
+			sigHash: "b6296fff0c1f23de4906d58144b00a2db13ad25e49b4b8573a62efeecb544dd7",
+		},
+		{
+			name:    "Count/ML-DSA-65/64",
+			params:  MLDSA65(),
+			seed:    "26b605c78ac762fa1634c6f91dd117c4fbff7f3a7e7781f0cc83b6281f04ad7f",
+			keyHash: "5da13e571df80867a8f27e0ff81be7252a1abf89b3d6a03d4036af643efbb04b",
+			message: "c9b07e7ddc0274468f312f5c692a54ac73d1e34d8638e20a2cd3c788f27d4355",
+			sigHash: "12a4637e3a833a5a2a46f6a991399e544b62a230b7aa82f7366840ff6a88de61",
+		},
+		{
+			name:    "Count/ML-DSA-65/73",
+			params:  MLDSA65(),
+			seed:    "9191cf381bee17475c011986efb6afb1efa6997442fd33427353f1da1aa39fc0",
+			keyHash: "7930d4e52ba03b61daa57743b39e291d824dc156356c6b1a8232574d5c8bdd08",
+			message: "e616e36e81aa1ec39262109421ae0ddda5e3b5a8f4a252bca27ae882538df618",
+			sigHash: "3d758ace312433d780403b3d4273171fb93d008b395352142c6dc5173e517310",
+		},
+		{
+			name:    "Count/ML-DSA-65/66",
+			params:  MLDSA65(),
+			seed:    "516912c7b90a3dbe009b7478dbcaf0f5c5c9ed9699a20d0ca56cc516e5a444cd",
+			keyHash: "0fd15951b93a4d19446b48d47d32d2ca2253ff43bb8cccb34c07e5f1a3181b7a",
+			message: "9247ca75f9456226a0c783dabcc33ff5b4b489575aded543e74b29b45f9c8ef2",
+			sigHash: "e5ce267800edf33588451050f9b4a5bf97030d045132a7e3ed9210e74028d23b",
+		},
+		{
+			name:    "Count/ML-DSA-65/65",
+			params:  MLDSA65(),
+			seed:    "d4b841f882d50ab9e590066bafaba0f0d04d32641c0b978e54ccaa69a6e8d2c4",
+			keyHash: "0039c128dde6923ea08ff14f5c5c66dcb282b471fd1917dbebe07c8c45b73f8a",
+			message: "175231657b0f3c7065947999467c342064f29bfaeb553e97561407d5560e3aeb",
+			sigHash: "8830ea254af2854bf67c2b907e2321c94fd6efb2fdaa77669fc3a5c4426c57c9",
+		},
+		{
+			name:    "Count/ML-DSA-65/64",
+			params:  MLDSA65(),
+			seed:    "5492eb8d811072c030a30cc66b23a173059eba0d4868ccb92fbe2510b4a5915f",
+			keyHash: "573dcd99c86dae81f6f80cb00af40846028ea8f9fe63102fe4a78238bc7b660e",
+			message: "33d2753ed87d0003b44c1af5f72eb931f559c6b4931af7e249f65d3fa7613295",
+			sigHash: "84d4af50933d6e13d4332b86af0692a66f5030ab01c2eac4131a5eebf78ce9e5",
+		},
+		{
+			name:    "Count/ML-DSA-87/64",
+			params:  MLDSA87(),
+			seed:    "b5c07ecefe9e7c3b885fdef032bdf9f807b4011e2dfe6806c088d2081631c8eb",
+			keyHash: "5d22f4c40f6eeb96bb891db15884ed4b0009ea02a24d9d1e9adfc81c7a42ea7f",
+			message: "d1d5c2d167d6e62906790a5fedf5a0a754cfaf47e6a11aeb93fb8c41934c31f8",
+			sigHash: "54f0a9cb26f98b394a35918eca6760ebd10753fc5cdba8be508873ad83538131",
+		},
+		{
+			name:    "Count/ML-DSA-87/65",
+			params:  MLDSA87(),
+			seed:    "e8fc3c9fad711dda2946334fbbd331468d6e9ab48eb86dcd03f300a17aebc5e5",
+			keyHash: "b6c4dc9b20ce5d0f445931ee316cf0676e806d1a6a98868881d060ea27ceb139",
+			message: "3b435f7a2ce431c7ab8eae0991c5dac610827c99d27803046fbc6c567d6b71f2",
+			sigHash: "e337495f08773f14fb26a3e229b9b26d086644c7fdc300267f9dcdd5d78db849",
+		},
+		{
+			name:    "Count/ML-DSA-87/64",
+			params:  MLDSA87(),
+			seed:    "151f80886d6ce8c3b428964fe02c40ca0c8effa100ee089e54d785344fccf719",
+			keyHash: "127972c33323fefbf6b69c19e0c86f41558d9ab2b1a8ad6f39bd0a0245dc8d7e",
+			message: "c628ce94d2aa99aa50cf15b147d4f9a9c62a3d4612152de0a502c377f472d614",
+			sigHash: "99b552b21432544248bff47ac8f24cb78dbb25c9683f3adcb75614bed58a0358",
+		},
+		{
+			name:    "Count/ML-DSA-87/64",
+			params:  MLDSA87(),
+			seed:    "48beffb4c97e59e474e1906f39888be5ae62f6a011c05ef6a6b8d1e54f2171b7",
+			keyHash: "72da77cf563cbb530129f60129af989ca4036ba1058267bfba34a2c70be803c4",
+			message: "d2756a8fb4e47f796af704ed0fc8c6e573d42dfab443b329f00f8db2ff12c465",
+			sigHash: "e643914b8556d05360c65eb3e7a06be7c398b82d49973eefdc711e65b11eb5e8",
+		},
+		{
+			name:    "Count/ML-DSA-87/69",
+			params:  MLDSA87(),
+			seed:    "fe2da9dd93a077fcb6452ac88d0a5762eb896baaac6ce7d01cb1370ba8322390",
+			keyHash: "7422dbe3f476ffe41a4efb33f3ddfd8b328029ba3050603866c36cfbc2ee4b87",
+			message: "a86b29adf2300d2636e21d4a350cd18e55a254379c3659a7a95d8734cec1f005",
+			sigHash: "8d25818dd972fff5b9e9b4cc534a95100a1340c1c81d1486a68939d340e0a58b",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			sk, err := NewPrivateKey(test.params, fromHex(t, test.seed))
+			if err != nil {
+				t.Fatalf("Failed to create private key: %s", err.Error())
+			}
+			pk := sk.PublicKey().Bytes()
+			keyHash := sha256.Sum256(append(pk, encodePrivateKey(sk)...))
+			if !bytes.Equal(keyHash[:], fromHex(t, test.keyHash)) {
+				t.Fatalf("Mismatched key hash %x", keyHash)
+			}
+
+			message := fromHex(t, test.message)
+			sig := sk.signInternal(message, make([]byte, 32))
+			sigHash := sha256.Sum256(sig)
+			if !bytes.Equal(sigHash[:], fromHex(t, test.sigHash)) {
+				t.Fatalf("Mismatched signature hash %x", sigHash)
+			}
+
+			parsed, err := NewPublicKey(test.params, pk)
+			if err != nil {
+				t.Fatalf("Failed to parse public key: %s", err.Error())
+			}
+			if !parsed.Equal(sk.Public()) {
+				t.Fatal("Mismatched public key after round trip")
+			}
+			if !parsed.verifyInternal(message, sig) {
+				t.Fatal("Failed to verify signature")
+			}
+			if parsed.verifyInternal(message[1:], sig) {
+				t.Fatal("Verification of another message should fail")
+			}
+		})
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	message := []byte("Lorem ipsum")
+	for _, params := range []*Parameters{MLDSA44(), MLDSA65(), MLDSA87()} {
+		params := params
+		t.Run(params.Name(), func(t *testing.T) {
+			sk, err := GenerateKey(params, rand.Reader)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %s", err.Error())
+			}
+			if len(sk.PublicKey().Bytes()) != params.PublicKeySize() {
+				t.Fatalf("Expected a %d bytes public key", params.PublicKeySize())
+			}
+			sig, err := sk.Sign(rand.Reader, message, crypto.Hash(0))
+			if err != nil {
+				t.Fatalf("Failed to sign message: %s", err.Error())
+			}
+			if len(sig) != params.SignatureSize() {
+				t.Fatalf("Expected a %d bytes signature, got %d", params.SignatureSize(), len(sig))
+			}
+			if !Verify(sk.PublicKey(), message, sig, nil) {
+				t.Fatal("Failed to verify signature")
+			}
+			if Verify(sk.PublicKey(), message, sig, &Options{Context: []byte("context")}) {
+				t.Fatal("Verification with another context should fail")
+			}
+			sig[len(sig)/2] ^= 1
+			if Verify(sk.PublicKey(), message, sig, nil) {
+				t.Fatal("Verification of a modified signature should fail")
+			}
+
+			other, err := NewPrivateKey(params, sk.Seed())
+			if err != nil {
+				t.Fatalf("Failed to create private key: %s", err.Error())
+			}
+			if !other.Equal(sk) {
+				t.Fatal("Keys derived from the same seed should be equal")
+			}
+			deterministic, err := other.Sign(nil, message, &Options{Context: []byte("context")})
+			if err != nil {
+				t.Fatalf("Failed to sign message: %s", err.Error())
+			}
+			again, err := sk.Sign(nil, message, &Options{Context: []byte("context")})
+			if err != nil {
+				t.Fatalf("Failed to sign message: %s", err.Error())
+			}
+			if !bytes.Equal(deterministic, again) {
+				t.Fatal("Deterministic signatures should be equal")
+			}
+			if !Verify(sk.PublicKey(), message, again, &Options{Context: []byte("context")}) {
+				t.Fatal("Failed to verify signature with context")
+			}
+		})
+	}
+	t.Run("Errors", func(t *testing.T) {
+		if _, err := NewPrivateKey(MLDSA44(), make([]byte, 31)); err == nil {
+			t.Fatal("Creating a key from a short seed should fail")
+		}
+		if _, err := NewPublicKey(MLDSA65(), make([]byte, MLDSA44().PublicKeySize())); err == nil {
+			t.Fatal("Parsing a public key of another parameter set should fail")
+		}
+		sk, err := NewPrivateKey(MLDSA44(), make([]byte, SeedSize))
+		if err != nil {
+			t.Fatalf("Failed to create private key: %s", err.Error())
+		}
+		if _, err := sk.Sign(nil, message, crypto.SHA256); err == nil {
+			t.Fatal("Signing a pre-hashed message should fail")
+		}
+		if _, err := sk.Sign(nil, message, &Options{Context: make([]byte, 256)}); err == nil {
+			t.Fatal("Signing with a context longer than 255 bytes should fail")
+		}
+	})
+}
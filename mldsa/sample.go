@@ -0,0 +1,121 @@
+package mldsa
+
+import (
+	"encoding/binary"
+)
+
+// rejNTTPoly samples a polynomial in the NTT domain with uniform
+// coefficients, as described in Algorithm 30 of FIPS 204
+func rejNTTPoly(rho []byte, s, r byte) ringElement {
+	g := newShake128()
+	g.absorb(rho, []byte{s, r})
+	var f ringElement
+	var buf [3]byte
+	for j := 0; j < n; {
+		g.squeeze(buf[:])
+		z := uint32(buf[0]) | uint32(buf[1])<<8 | uint32(buf[2]&0x7F)<<16
+		if z < q {
+			f[j] = fieldElement(z)
+			j++
+		}
+	}
+	return f
+}
+
+// rejBoundedPoly samples a polynomial with coefficients in [-eta, eta], as
+// described in Algorithm 31 of FIPS 204
+func rejBoundedPoly(rho []byte, r uint16, eta int) ringElement {
+	h := newShake256()
+	var index [2]byte
+	binary.LittleEndian.PutUint16(index[:], r)
+	h.absorb(rho, index[:])
+	var f ringElement
+	var buf [1]byte
+	for j := 0; j < n; {
+		h.squeeze(buf[:])
+		for _, b := range []byte{buf[0] & 0x0F, buf[0] >> 4} {
+			if j == n {
+				break
+			}
+			switch {
+			case eta == 2 && b < 15:
+				f[j] = fieldFromInt(2 - int32(b%5))
+				j++
+			case eta == 4 && b < 9:
+				f[j] = fieldFromInt(4 - int32(b))
+				j++
+			}
+		}
+	}
+	return f
+}
+
+// sampleInBall samples a polynomial with `tau` coefficients in {-1, 1} and
+// the other ones equal to 0, as described in Algorithm 29 of FIPS 204
+func sampleInBall(seed []byte, tau int) ringElement {
+	h := newShake256()
+	h.absorb(seed)
+	var s [8]byte
+	h.squeeze(s[:])
+	signs := binary.LittleEndian.Uint64(s[:])
+
+	var c ringElement
+	var buf [1]byte
+	for i := n - tau; i < n; i++ {
+		for {
+			h.squeeze(buf[:])
+			if int(buf[0]) <= i {
+				break
+			}
+		}
+		j := buf[0]
+		c[i] = c[j]
+		c[j] = 1
+		if signs&1 == 1 {
+			c[j] = q - 1
+		}
+		signs >>= 1
+	}
+	return c
+}
+
+// expandA samples the k×l matrix Â in the NTT domain, as described in
+// Algorithm 32 of FIPS 204. The element of row r and column s is at index
+// r·l+s.
+func expandA(rho []byte, k, l int) []ringElement {
+	a := make([]ringElement, k*l)
+	for r := 0; r < k; r++ {
+		for s := 0; s < l; s++ {
+			a[r*l+s] = rejNTTPoly(rho, byte(s), byte(r))
+		}
+	}
+	return a
+}
+
+// expandS samples the secret vectors s1 and s2, as described in Algorithm
+// 33 of FIPS 204
+func expandS(rho []byte, k, l, eta int) ([]ringElement, []ringElement) {
+	s1 := make([]ringElement, l)
+	for r := range s1 {
+		s1[r] = rejBoundedPoly(rho, uint16(r), eta)
+	}
+	s2 := make([]ringElement, k)
+	for r := range s2 {
+		s2[r] = rejBoundedPoly(rho, uint16(l+r), eta)
+	}
+	return s1, s2
+}
+
+// expandMask samples the mask vector y, as described in Algorithm 34 of
+// FIPS 204
+func expandMask(rho []byte, mu int, l int, gamma1 uint32) []ringElement {
+	bits := 1 + bitLen(gamma1-1)
+	y := make([]ringElement, l)
+	var index [2]byte
+	for r := range y {
+		binary.LittleEndian.PutUint16(index[:], uint16(mu+r))
+		v := shake256(int(32*bits), rho, index[:])
+		y[r] = bitUnpack(v, gamma1-1, gamma1)
+	}
+	return y
+}
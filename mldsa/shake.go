@@ -0,0 +1,136 @@
+package mldsa
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// SHAKE128 and SHAKE256 are implemented here, as described in
+// https://nvlpubs.nist.gov/nistpubs/FIPS/NIST.FIPS.202.pdf, because
+// crypto/sha3 is not available in the Go versions supported by this module
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRotations and keccakLanes drive the combined ρ and π steps, which
+// move each lane along a single cycle through the state
+var keccakRotations = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+var keccakLanes = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+
+// keccakF1600 applies the Keccak-p[1600, 24] permutation to `a`
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+	for round := 0; round < 24; round++ {
+		// θ
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			t := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[y+x] ^= t
+			}
+		}
+		// ρ and π
+		t := a[1]
+		for i, lane := range keccakLanes {
+			t, a[lane] = a[lane], bits.RotateLeft64(t, keccakRotations[i])
+		}
+		// χ
+		for y := 0; y < 25; y += 5 {
+			copy(c[:], a[y:y+5])
+			for x := 0; x < 5; x++ {
+				a[y+x] = c[x] ^ (^c[(x+1)%5] & c[(x+2)%5])
+			}
+		}
+		// ι
+		a[0] ^= keccakRoundConstants[round]
+	}
+}
+
+// shake is a SHAKE extendable-output function. Its input is absorbed
+// first, after which any amount of output can be squeezed.
+type shake struct {
+	state     [25]uint64
+	buf       [200]byte
+	rate      int
+	pos       int
+	squeezing bool
+}
+
+func newShake128() *shake {
+	return &shake{rate: 168}
+}
+
+func newShake256() *shake {
+	return &shake{rate: 136}
+}
+
+// absorb feeds each of `inputs` to the function
+func (s *shake) absorb(inputs ...[]byte) {
+	for _, in := range inputs {
+		for len(in) > 0 {
+			n := copy(s.buf[s.pos:s.rate], in)
+			s.pos += n
+			in = in[n:]
+			if s.pos == s.rate {
+				s.permute()
+				s.pos = 0
+			}
+		}
+	}
+}
+
+// squeeze fills `out` with the next output bytes
+func (s *shake) squeeze(out []byte) {
+	if !s.squeezing {
+		// Pad the input with the SHAKE domain separation bits and pad10*1
+		for i := s.pos; i < s.rate; i++ {
+			s.buf[i] = 0
+		}
+		s.buf[s.pos] ^= 0x1F
+		s.buf[s.rate-1] ^= 0x80
+		s.permute()
+		s.pos = 0
+		s.squeezing = true
+	}
+	for len(out) > 0 {
+		if s.pos == s.rate {
+			s.permute()
+			s.pos = 0
+		}
+		n := copy(out, s.buf[s.pos:s.rate])
+		s.pos += n
+		out = out[n:]
+	}
+}
+
+// permute xors the buffered input into the state when absorbing, applies
+// the permutation, and buffers the resulting output block
+func (s *shake) permute() {
+	if !s.squeezing {
+		for i := 0; i < s.rate/8; i++ {
+			s.state[i] ^= binary.LittleEndian.Uint64(s.buf[8*i:])
+		}
+	}
+	keccakF1600(&s.state)
+	for i := 0; i < s.rate/8; i++ {
+		binary.LittleEndian.PutUint64(s.buf[8*i:], s.state[i])
+	}
+}
+
+// shake256 returns `size` bytes of SHAKE256 output for the concatenation
+// of `inputs`
+func shake256(size int, inputs ...[]byte) []byte {
+	s := newShake256()
+	s.absorb(inputs...)
+	out := make([]byte, size)
+	s.squeeze(out)
+	return out
+}